@@ -0,0 +1,1207 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// Statement codes, as written by cmd/compile's noder (codeStmt).
+const (
+	stmtEnd = iota
+	stmtLabel
+	stmtBlock
+	stmtExpr
+	stmtSend
+	stmtAssign
+	stmtAssignOp
+	stmtIncDec
+	stmtBranch
+	stmtCall
+	stmtReturn
+	stmtIf
+	stmtFor
+	stmtSwitch
+	stmtSelect
+)
+
+// Expression codes, as written by cmd/compile's noder (codeExpr).
+const (
+	exprConst = iota
+	exprLocal
+	exprGlobal
+	exprCompLit
+	exprFuncLit
+	exprFieldVal
+	exprMethodVal
+	exprMethodExpr
+	exprIndex
+	exprSlice
+	exprAssert
+	exprUnaryOp
+	exprBinaryOp
+	exprCall
+	exprConvert
+	exprNew
+	exprMake
+	exprSizeof
+	exprAlignof
+	exprOffsetof
+	exprZero
+	exprFuncInst
+	exprRecv
+	exprReshape
+	exprRuntimeBuiltin
+)
+
+// Assignee codes, as written by cmd/compile's noder (codeAssign).
+const (
+	assignBlank = iota
+	assignDef
+	assignExpr
+)
+
+// Operators are encoded as cmd/compile's ir.Op values, which are
+// private to the compiler and may be renumbered between releases.
+const (
+	opADD      = 6
+	opSUB      = 7
+	opOR       = 8
+	opXOR      = 9
+	opADDR     = 11
+	opANDAND   = 12
+	opEQ       = 57
+	opNE       = 58
+	opLT       = 59
+	opLE       = 60
+	opGE       = 61
+	opGT       = 62
+	opDEREF    = 63
+	opMUL      = 74
+	opDIV      = 75
+	opMOD      = 76
+	opLSH      = 77
+	opRSH      = 78
+	opAND      = 79
+	opANDNOT   = 80
+	opNOT      = 82
+	opBITNOT   = 83
+	opPLUS     = 84
+	opNEG      = 85
+	opOROR     = 86
+	opRECV     = 100
+	opBREAK    = 116
+	opCONTINUE = 118
+	opDEFER    = 119
+	opFALL     = 120
+	opGOTO     = 122
+	opGO       = 125
+)
+
+// Operator precedences, as in go/token, extended with levels for
+// unary and primary expressions.
+const (
+	precUnary   = 6
+	precPrimary = 7
+)
+
+// A binaryOp is the Go syntax of a binary operator.
+type binaryOp struct {
+	tok  string
+	prec int
+}
+
+var binaryOps = map[int]binaryOp{
+	opOROR:   {"||", 1},
+	opANDAND: {"&&", 2},
+	opEQ:     {"==", 3},
+	opNE:     {"!=", 3},
+	opLT:     {"<", 3},
+	opLE:     {"<=", 3},
+	opGT:     {">", 3},
+	opGE:     {">=", 3},
+	opADD:    {"+", 4},
+	opSUB:    {"-", 4},
+	opOR:     {"|", 4},
+	opXOR:    {"^", 4},
+	opMUL:    {"*", 5},
+	opDIV:    {"/", 5},
+	opMOD:    {"%", 5},
+	opLSH:    {"<<", 5},
+	opRSH:    {">>", 5},
+	opAND:    {"&", 5},
+	opANDNOT: {"&^", 5},
+}
+
+var unaryOps = map[int]string{
+	opADDR:   "&",
+	opDEREF:  "*",
+	opNOT:    "!",
+	opBITNOT: "^",
+	opPLUS:   "+",
+	opNEG:    "-",
+	opRECV:   "<-",
+}
+
+var keywordOps = map[int]string{
+	opBREAK:    "break",
+	opCONTINUE: "continue",
+	opFALL:     "fallthrough",
+	opGOTO:     "goto",
+	opDEFER:    "defer",
+	opGO:       "go",
+}
+
+// binaryOpIdx returns the binary operator with the given code.
+func binaryOpIdx(op int) binaryOp {
+	if b, ok := binaryOps[op]; ok {
+		return b
+	}
+	return binaryOp{fmt.Sprintf("op#%d", op), 1}
+}
+
+func opString(ops map[int]string, op int) string {
+	if s, ok := ops[op]; ok {
+		return s
+	}
+	return fmt.Sprintf("op#%d", op)
+}
+
+// A bodyReader decodes a SectionBody element into Go-like source.
+// Local variables are unnamed in the bitstream, so their names are
+// recovered from the declarations that introduce them.
+type bodyReader struct {
+	*reader
+
+	locals      []string
+	closureVars []string
+}
+
+// A bodyExpr is a decoded expression.
+type bodyExpr struct {
+	s    string
+	prec int
+
+	// typ is the expression's type, if it was recorded.
+	typ   typeInfo
+	typed bool
+
+	// builtin is the name of the predeclared or package unsafe
+	// function the expression refers to, if any.
+	builtin string
+}
+
+// at returns the expression's syntax as an operand of an operator with
+// precedence prec, parenthesized if needed.
+func (x bodyExpr) at(prec int) string {
+	if x.prec < prec {
+		return "(" + x.s + ")"
+	}
+	return x.s
+}
+
+// funcBody decodes the specified SectionBody element: the body of a
+// function with the given receiver, if any, and signature. The
+// function's closure variables, if it is a function literal, are
+// named by closureVars. It returns the body's statements, or false if
+// the function has no Go body (e.g., it is implemented in assembly).
+func (pr *pkgReader) funcBody(idx pkgbits.Index, dict *readerDict, recv *param, sig *signature, closureVars []string) ([]string, bool) {
+	b := &bodyReader{
+		reader:      pr.newReader(pkgbits.SectionBody, idx, pkgbits.SyncFuncBody),
+		closureVars: closureVars,
+	}
+	b.dict = dict
+
+	if recv != nil {
+		b.addLocal(recv.name)
+	}
+	for _, p := range sig.params {
+		b.addLocal(p.name)
+	}
+	for _, p := range sig.results {
+		b.addLocal(p.name)
+	}
+
+	if !b.Bool() {
+		return nil, false
+	}
+	stmts := b.stmts()
+	b.pos()
+	return stmts, true
+}
+
+// @@@ Locals
+
+func (b *bodyReader) addLocal(name string) {
+	b.Sync(pkgbits.SyncAddLocal)
+	if b.p.SyncMarkers() {
+		b.Int()
+	}
+	if b.Bool() { // dictionary index, for variables of derived type
+		b.Len()
+	}
+
+	if name == "" {
+		name = "_"
+	}
+	b.locals = append(b.locals, name)
+}
+
+func (b *bodyReader) useLocal() string {
+	b.Sync(pkgbits.SyncUseObjLocal)
+	if b.Bool() {
+		return b.locals[b.Len()]
+	}
+	return b.closureVars[b.Len()]
+}
+
+// @@@ Statements
+
+// stmts reads a statement list. Each statement's syntax may span
+// several lines.
+func (b *bodyReader) stmts() []string {
+	var stmts []string
+	b.Sync(pkgbits.SyncStmts)
+	for {
+		tag := b.Code(pkgbits.SyncStmt1)
+		if tag == stmtEnd {
+			b.Sync(pkgbits.SyncStmtsEnd)
+			return stmts
+		}
+		stmts = append(stmts, b.stmt1(tag)...)
+	}
+}
+
+// simpleStmt reads a statement list that appears in a statement
+// header, such as the init statement of an if statement.
+func (b *bodyReader) simpleStmt() string {
+	return strings.Join(b.stmts(), "; ")
+}
+
+func (b *bodyReader) stmt1(tag int) []string {
+	switch tag {
+	default:
+		panic(fmt.Errorf("unexpected statement code: %d", tag))
+
+	case stmtAssign:
+		b.pos()
+		lhs, defs, def := b.assignList()
+		rhs := b.multiExpr()
+		if len(rhs) == 0 {
+			return defs
+		}
+		tok := " = "
+		if def {
+			tok = " := "
+		}
+		return []string{strings.Join(lhs, ", ") + tok + strings.Join(rhs, ", ")}
+
+	case stmtAssignOp:
+		op := binaryOpIdx(b.op())
+		lhs := b.expr()
+		b.pos()
+		rhs := b.expr()
+		return []string{lhs.s + " " + op.tok + "= " + rhs.s}
+
+	case stmtIncDec:
+		op := b.op()
+		x := b.expr()
+		b.pos()
+		if op == opSUB {
+			return []string{x.s + "--"}
+		}
+		return []string{x.s + "++"}
+
+	case stmtBlock:
+		return []string{"{\n" + indent(b.blockStmt()) + "}"}
+
+	case stmtBranch:
+		b.pos()
+		s := opString(keywordOps, b.op())
+		if label := b.optLabel(); label != "" {
+			s += " " + label
+		}
+		return []string{s}
+
+	case stmtCall:
+		b.pos()
+		op := b.op()
+		s := opString(keywordOps, op) + " " + b.expr().s
+		if op == opDEFER && b.Bool() {
+			// Defers within range-over-func loop bodies are attached
+			// to the enclosing function's frame.
+			s += " // at " + b.expr().s
+		}
+		return []string{s}
+
+	case stmtExpr:
+		return []string{b.expr().s}
+
+	case stmtFor:
+		return []string{b.forStmt()}
+
+	case stmtIf:
+		return []string{b.ifStmt()}
+
+	case stmtLabel:
+		b.pos()
+		return []string{b.label() + ":"}
+
+	case stmtReturn:
+		b.pos()
+		if results := b.multiExpr(); len(results) > 0 {
+			return []string{"return " + strings.Join(results, ", ")}
+		}
+		return []string{"return"}
+
+	case stmtSelect:
+		return []string{b.selectStmt()}
+
+	case stmtSend:
+		b.pos()
+		ch := b.expr()
+		value := b.expr()
+		return []string{ch.s + " <- " + value.s}
+
+	case stmtSwitch:
+		return []string{b.switchStmt()}
+	}
+}
+
+// assignList reads the left-hand side of an assignment. It returns the
+// assignees, the declarations of any new variables, and whether there
+// were any.
+func (b *bodyReader) assignList() (lhs, decls []string, def bool) {
+	lhs = make([]string, b.Len())
+	for i := range lhs {
+		switch tag := b.Code(pkgbits.SyncAssign); tag {
+		default:
+			panic(fmt.Errorf("unexpected assignee code: %d", tag))
+
+		case assignBlank:
+			lhs[i] = "_"
+
+		case assignDef:
+			b.pos()
+			_, name := b.localIdent()
+			typ := b.typInfo()
+			b.addLocal(name)
+			lhs[i] = name
+			decls = append(decls, "var "+name+" "+b.p.typeString(typ, b.dict))
+			def = true
+
+		case assignExpr:
+			lhs[i] = b.expr().s
+		}
+	}
+	return lhs, decls, def
+}
+
+func (b *bodyReader) blockStmt() []string {
+	b.Sync(pkgbits.SyncBlockStmt)
+	b.openScope()
+	stmts := b.stmts()
+	b.closeScope()
+	return stmts
+}
+
+func (b *bodyReader) forStmt() string {
+	b.Sync(pkgbits.SyncForStmt)
+	b.openScope()
+
+	var head string
+	if b.Bool() { // range
+		b.pos()
+		lhs, _, def := b.assignList()
+		x := b.expr()
+		if b.isMap(x) {
+			b.rtype()
+		}
+		for i, name := range lhs {
+			if i < 2 && name != "_" {
+				b.convRTTI()
+			}
+		}
+
+		head = "for range " + x.s
+		if len(lhs) > 0 {
+			tok := " = "
+			if def {
+				tok = " := "
+			}
+			head = "for " + strings.Join(lhs, ", ") + tok + "range " + x.s
+		}
+	} else {
+		b.pos()
+		init := b.simpleStmt()
+		var cond string
+		if b.Bool() {
+			cond = b.expr().s
+		}
+		post := b.simpleStmt()
+
+		switch {
+		case init == "" && post == "" && cond == "":
+			head = "for"
+		case init == "" && post == "":
+			head = "for " + cond
+		default:
+			head = "for " + init + "; " + cond + "; " + post
+		}
+	}
+
+	body := b.blockStmt()
+	b.Bool() // distinct variables per iteration
+	b.closeAnotherScope()
+
+	return head + " {\n" + indent(body) + "}"
+}
+
+func (b *bodyReader) ifStmt() string {
+	b.Sync(pkgbits.SyncIfStmt)
+	b.openScope()
+	b.pos()
+	head := "if "
+	if init := b.simpleStmt(); init != "" {
+		head += init + "; "
+	}
+	head += b.expr().s
+
+	// The writer drops branches that are statically unreachable.
+	var then, els []string
+	staticCond := b.Int()
+	switch {
+	case staticCond > 0:
+		head += " { // always true"
+	case staticCond < 0:
+		head += " { // always false"
+	default:
+		head += " {"
+	}
+	if staticCond >= 0 {
+		then = b.blockStmt()
+	} else {
+		b.pos()
+	}
+	if staticCond <= 0 {
+		els = b.stmts()
+	}
+	b.closeAnotherScope()
+
+	s := head + "\n" + indent(then) + "}"
+	switch {
+	case len(els) == 1 && strings.HasPrefix(els[0], "if "):
+		s += " else " + els[0]
+	case len(els) > 0:
+		s += " else {\n" + indent(els) + "}"
+	}
+	return s
+}
+
+func (b *bodyReader) selectStmt() string {
+	b.Sync(pkgbits.SyncSelectStmt)
+	b.pos()
+
+	var buf strings.Builder
+	buf.WriteString("select {\n")
+	n := b.Len()
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.closeScope()
+		}
+		b.openScope()
+		b.pos()
+		if comm := b.simpleStmt(); comm != "" {
+			buf.WriteString("case " + comm + ":\n")
+		} else {
+			buf.WriteString("default:\n")
+		}
+		buf.WriteString(indent(b.stmts()))
+	}
+	if n > 0 {
+		b.closeScope()
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+func (b *bodyReader) switchStmt() string {
+	b.Sync(pkgbits.SyncSwitchStmt)
+	b.openScope()
+	b.pos()
+
+	head := "switch "
+	if init := b.simpleStmt(); init != "" {
+		head += init + "; "
+	}
+
+	typeSwitch := b.Bool()
+	var ident string
+	if typeSwitch {
+		b.pos()
+		if b.Bool() {
+			b.pos()
+			_, ident = b.localIdent()
+			head += ident + " := "
+		}
+		head += b.expr().at(precPrimary) + ".(type) "
+	} else if b.Bool() {
+		head += b.expr().s + " "
+	}
+
+	var buf strings.Builder
+	buf.WriteString(head + "{\n")
+	n := b.Len()
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.closeScope()
+		}
+		b.openScope()
+		b.pos()
+
+		var cases []string
+		if typeSwitch {
+			cases = make([]string, b.Len())
+			for i := range cases {
+				if b.Bool() {
+					cases[i] = "nil"
+				} else {
+					cases[i] = b.exprType()
+				}
+			}
+		} else {
+			cases = b.exprList()
+		}
+		if len(cases) > 0 {
+			buf.WriteString("case " + strings.Join(cases, ", ") + ":\n")
+		} else {
+			buf.WriteString("default:\n")
+		}
+
+		if ident != "" {
+			b.pos()
+			b.typInfo()
+			b.addLocal(ident)
+		}
+		buf.WriteString(indent(b.stmts()))
+	}
+	if n > 0 {
+		b.closeScope()
+	}
+	b.closeScope()
+
+	buf.WriteString("}")
+	return buf.String()
+}
+
+func (b *bodyReader) label() string {
+	b.Sync(pkgbits.SyncLabel)
+	return b.String()
+}
+
+func (b *bodyReader) optLabel() string {
+	b.Sync(pkgbits.SyncOptLabel)
+	if b.Bool() {
+		return b.label()
+	}
+	return ""
+}
+
+func (b *bodyReader) openScope() {
+	b.Sync(pkgbits.SyncOpenScope)
+	b.pos()
+}
+
+func (b *bodyReader) closeScope() {
+	b.Sync(pkgbits.SyncCloseScope)
+	b.pos()
+	b.closeAnotherScope()
+}
+
+func (b *bodyReader) closeAnotherScope() {
+	b.Sync(pkgbits.SyncCloseAnotherScope)
+}
+
+// @@@ Expressions
+
+func (b *bodyReader) expr() bodyExpr {
+	switch tag := b.Code(pkgbits.SyncExpr); tag {
+	default:
+		panic(fmt.Errorf("unexpected expression code: %d", tag))
+
+	case exprLocal:
+		return bodyExpr{s: b.useLocal(), prec: precPrimary}
+
+	case exprGlobal:
+		info := b.objInfo()
+		x := bodyExpr{s: b.objString(info), prec: precPrimary}
+		if path, name, _ := b.p.PeekObj(info.idx); path == "builtin" || path == "unsafe" {
+			x.builtin = name
+		}
+		return x
+
+	case exprFuncInst:
+		b.pos()
+		return bodyExpr{s: b.funcInst(), prec: precPrimary}
+
+	case exprConst:
+		b.pos()
+		typ := b.typInfo()
+		s := valueString(b.Value())
+		prec := precPrimary
+		if strings.HasPrefix(s, "-") {
+			prec = precUnary
+		}
+		return bodyExpr{s: s, prec: prec, typ: typ, typed: true}
+
+	case exprZero:
+		b.pos()
+		typ := b.typInfo()
+		return bodyExpr{s: "nil", prec: precPrimary, typ: typ, typed: true}
+
+	case exprCompLit:
+		return b.compLit()
+
+	case exprFuncLit:
+		return bodyExpr{s: b.funcLit(), prec: precPrimary}
+
+	case exprFieldVal:
+		x := b.expr()
+		b.pos()
+		_, sel := b.selector()
+		return bodyExpr{s: x.at(precPrimary) + "." + sel, prec: precPrimary}
+
+	case exprMethodVal:
+		recv := b.expr()
+		b.pos()
+		return bodyExpr{s: recv.at(precPrimary) + "." + b.methodExpr(), prec: precPrimary}
+
+	case exprMethodExpr:
+		recv := b.p.typeString(b.typInfo(), b.dict)
+		for i, n := 0, b.Len(); i < n; i++ {
+			b.Len() // implicit embedded field index
+		}
+		if !b.Bool() { // needs deref
+			b.Bool() // needs addr
+		}
+		b.pos()
+		if strings.HasPrefix(recv, "*") {
+			recv = "(" + recv + ")"
+		}
+		return bodyExpr{s: recv + "." + b.methodExpr(), prec: precPrimary}
+
+	case exprIndex:
+		x := b.expr()
+		b.pos()
+		index := b.expr()
+		if b.isMap(x) {
+			b.rtype()
+		}
+		return bodyExpr{s: x.at(precPrimary) + "[" + index.s + "]", prec: precPrimary}
+
+	case exprSlice:
+		x := b.expr()
+		b.pos()
+		var index [3]string
+		for i := range index {
+			if b.Bool() {
+				index[i] = b.expr().s
+			}
+		}
+		s := x.at(precPrimary) + "[" + index[0] + ":" + index[1]
+		if index[2] != "" {
+			s += ":" + index[2]
+		}
+		return bodyExpr{s: s + "]", prec: precPrimary}
+
+	case exprAssert:
+		x := b.expr()
+		b.pos()
+		typ := b.exprType()
+		b.rtype()
+		return bodyExpr{s: x.at(precPrimary) + ".(" + typ + ")", prec: precPrimary}
+
+	case exprUnaryOp:
+		op := opString(unaryOps, b.op())
+		b.pos()
+		x := b.at(precUnary)
+		// Avoid gluing operators together, as in "- -x".
+		if strings.HasPrefix(x, op[:1]) {
+			x = "(" + x + ")"
+		}
+		return bodyExpr{s: op + x, prec: precUnary}
+
+	case exprBinaryOp:
+		op := binaryOpIdx(b.op())
+		x := b.expr()
+		b.pos()
+		y := b.expr()
+		return bodyExpr{s: x.at(op.prec) + " " + op.tok + " " + y.at(op.prec+1), prec: op.prec}
+
+	case exprRecv:
+		// The receiver of a method call, after any implicit field
+		// selections and address or dereference operations, which are
+		// not shown.
+		x := b.expr()
+		b.pos()
+		for i, n := 0, b.Len(); i < n; i++ {
+			b.Len()
+		}
+		if !b.Bool() {
+			b.Bool()
+		}
+		return x
+
+	case exprCall:
+		var fun string
+		var builtin string
+		if b.Bool() { // method call
+			recv := b.expr()
+			fun = recv.at(precPrimary) + "." + b.methodExpr()
+		} else if b.Bool() { // call to instantiated generic function
+			b.pos()
+			fun = b.funcInst()
+		} else {
+			x := b.expr()
+			fun = x.at(precPrimary)
+			builtin = x.builtin
+		}
+		b.pos()
+		args := b.multiExpr()
+		if b.Bool() {
+			args[len(args)-1] += "..."
+		}
+		switch builtin {
+		case "append", "copy", "delete", "Slice":
+			b.rtype()
+		}
+		return bodyExpr{s: fun + "(" + strings.Join(args, ", ") + ")", prec: precPrimary}
+
+	case exprMake:
+		b.pos()
+		args := append([]string{b.exprType()}, b.exprs()...)
+		b.rtype()
+		return bodyExpr{s: "make(" + strings.Join(args, ", ") + ")", prec: precPrimary}
+
+	case exprNew:
+		b.pos()
+		return bodyExpr{s: "new(" + b.exprType() + ")", prec: precPrimary}
+
+	case exprSizeof, exprAlignof:
+		b.pos()
+		name := "unsafe.Sizeof"
+		if tag == exprAlignof {
+			name = "unsafe.Alignof"
+		}
+		return bodyExpr{s: name + "(" + b.p.typeString(b.typInfo(), b.dict) + ")", prec: precPrimary}
+
+	case exprOffsetof:
+		b.pos()
+		typ := b.typInfo()
+		s := b.p.typeString(typ, b.dict)
+		for i := b.Len(); i >= 0; i-- {
+			f := b.Len()
+			name := fmt.Sprintf("field#%d", f)
+			if st := b.p.coreType(typ, b.dict); st != nil && st.code == pkgbits.TypeStruct && f < len(st.fields) {
+				name = st.fields[f].name
+				typ = st.fields[f].typ
+			}
+			s += "." + name
+		}
+		return bodyExpr{s: "unsafe.Offsetof(" + s + ")", prec: precPrimary}
+
+	case exprReshape:
+		typ := b.typInfo()
+		x := b.expr()
+		x.typ, x.typed = typ, true
+		return x
+
+	case exprConvert:
+		implicit := b.Bool()
+		typ := b.typInfo()
+		b.pos()
+		b.convRTTI()
+		b.Bool() // destination is a type parameter
+		b.Bool() // source and destination types are identical
+		x := b.expr()
+		if implicit {
+			x.typ, x.typed = typ, true
+			return x
+		}
+		s := b.p.typeString(typ, b.dict)
+		if strings.HasPrefix(s, "*") || strings.HasPrefix(s, "<-") || strings.HasPrefix(s, "func") {
+			s = "(" + s + ")"
+		}
+		return bodyExpr{s: s + "(" + x.s + ")", prec: precPrimary, typ: typ, typed: true}
+
+	case exprRuntimeBuiltin:
+		return bodyExpr{s: "runtime." + b.String(), prec: precPrimary}
+	}
+}
+
+// at reads an expression as an operand of an operator with
+// precedence prec.
+func (b *bodyReader) at(prec int) string {
+	return b.expr().at(prec)
+}
+
+func (b *bodyReader) exprList() []string {
+	b.Sync(pkgbits.SyncExprList)
+	return b.exprs()
+}
+
+func (b *bodyReader) exprs() []string {
+	b.Sync(pkgbits.SyncExprs)
+	exprs := make([]string, b.Len())
+	for i := range exprs {
+		exprs[i] = b.expr().s
+	}
+	return exprs
+}
+
+// multiExpr reads the values of an assignment, return statement or
+// call. If a single call provides several values, it is the only
+// element of the result.
+func (b *bodyReader) multiExpr() []string {
+	b.Sync(pkgbits.SyncMultiExpr)
+
+	if b.Bool() { // N:1
+		b.pos()
+		x := b.expr()
+		for i, n := 0, b.Len(); i < n; i++ {
+			b.typInfo()
+			if b.Bool() { // implicit conversion
+				b.typInfo()
+				b.convRTTI()
+			}
+		}
+		return []string{x.s}
+	}
+
+	exprs := make([]string, b.Len())
+	for i := range exprs {
+		exprs[i] = b.expr().s
+	}
+	return exprs
+}
+
+func (b *bodyReader) op() int {
+	b.Sync(pkgbits.SyncOp)
+	return b.Len()
+}
+
+func (b *bodyReader) compLit() bodyExpr {
+	b.Sync(pkgbits.SyncCompLit)
+	b.pos()
+	typ := b.typInfo()
+
+	s := b.p.typeString(typ, b.dict)
+	core := b.p.coreType(typ, b.dict)
+	if core != nil && core.code == pkgbits.TypePointer {
+		s = "&" + b.p.typeString(core.elem, b.dict)
+		core = b.p.coreType(core.elem, b.dict)
+	}
+	if core != nil && core.code == pkgbits.TypeMap {
+		b.rtype()
+	}
+
+	elems := b.compLitElems(core)
+	x := bodyExpr{s: s + "{" + strings.Join(elems, ", ") + "}", prec: precPrimary, typ: typ, typed: true}
+	if strings.HasPrefix(s, "&") {
+		x.prec = precUnary
+	}
+	return x
+}
+
+// compLitElems reads the elements of a composite literal whose type
+// has the given core type.
+func (b *bodyReader) compLitElems(core *typeNode) []string {
+	elems := make([]string, b.Len())
+	for i := range elems {
+		if core != nil && core.code == pkgbits.TypeStruct {
+			b.pos()
+			elems[i] = fieldName(core, b.Len()) + ": "
+		} else if b.Bool() { // keyed
+			b.pos()
+			elems[i] = b.expr().s + ": "
+		}
+		elems[i] += b.expr().s
+	}
+	return elems
+}
+
+// fieldName returns the name of the i'th field of struct type typ.
+func fieldName(typ *typeNode, i int) string {
+	if i < len(typ.fields) {
+		return typ.fields[i].name
+	}
+	return fmt.Sprintf("field#%d", i)
+}
+
+// funcLit reads a function literal, decoding its body in turn.
+func (b *bodyReader) funcLit() string {
+	b.Sync(pkgbits.SyncFuncLit)
+	b.pos()
+	sig := b.signature()
+	b.Bool() // synthesized for a range-over-func loop body
+
+	closureVars := make([]string, b.Len())
+	for i := range closureVars {
+		b.pos()
+		closureVars[i] = b.useLocal()
+	}
+
+	var buf strings.Builder
+	buf.WriteString("func")
+	b.p.writeSignature(&buf, sig, b.dict)
+
+	stmts, ok := b.p.funcBody(b.Reloc(pkgbits.SectionBody), b.dict, nil, sig, closureVars)
+	if !ok {
+		return buf.String()
+	}
+	buf.WriteString(" {\n")
+	buf.WriteString(indent(stmts))
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// methodExpr reads a method reference and returns the method's name.
+func (b *bodyReader) methodExpr() string {
+	b.typInfo() // receiver type
+	b.typInfo() // signature
+	b.pos()
+	_, name := b.selector()
+
+	if b.Bool() { // method of a type parameter
+		b.Len()
+	} else if b.Bool() { // dynamic subdictionary
+		b.Len()
+	} else if b.Bool() { // static dictionary
+		b.objInfo()
+	}
+	return name
+}
+
+// funcInst reads an instantiated generic function and returns its
+// name and type arguments.
+func (b *bodyReader) funcInst() string {
+	if b.Bool() { // dynamic subdictionary
+		idx := b.Len()
+		if b.dict != nil && idx < len(b.dict.subdicts) {
+			return b.objString(b.dict.subdicts[idx])
+		}
+		return fmt.Sprintf("subdict#%d", idx)
+	}
+	return b.objString(b.objInfo())
+}
+
+func (b *bodyReader) objString(info objInfo) string {
+	var buf strings.Builder
+	b.p.writeObj(&buf, info, b.dict)
+	return buf.String()
+}
+
+// exprType reads a type used as an expression, as in a conversion or
+// a type switch case, and returns its syntax.
+func (b *bodyReader) exprType() string {
+	b.Sync(pkgbits.SyncExprType)
+	b.pos()
+
+	var typ typeInfo
+	if b.Bool() {
+		typ, _ = b.itab()
+	} else {
+		typ = b.rtype()
+		b.Bool() // derived
+	}
+	return b.p.typeString(typ, b.dict)
+}
+
+// rtype reads a reference to run-time type information and returns
+// the type it describes.
+func (b *bodyReader) rtype() typeInfo {
+	b.Sync(pkgbits.SyncRType)
+	if b.Bool() { // derived type, from the runtime dictionary
+		idx := b.Len()
+		if b.dict != nil && idx < len(b.dict.rtypes) {
+			return b.dict.rtypes[idx]
+		}
+		return typeInfo{idx: pkgbits.Index(idx), derived: true}
+	}
+	return b.typInfo()
+}
+
+func (b *bodyReader) itab() (typ, iface typeInfo) {
+	typ = b.rtype()
+	iface = b.rtype()
+	if b.Bool() { // from the runtime dictionary
+		b.Len()
+	}
+	return typ, iface
+}
+
+func (b *bodyReader) convRTTI() {
+	b.Sync(pkgbits.SyncConvRTTI)
+	b.itab()
+}
+
+// isMap reports whether x is known to be a map.
+func (b *bodyReader) isMap(x bodyExpr) bool {
+	if !x.typed {
+		return false
+	}
+	core := b.p.coreType(x.typ, b.dict)
+	return core != nil && core.code == pkgbits.TypeMap
+}
+
+// coreType returns the type that determines the operations permitted
+// on values of the type referred to by info: its underlying type, or
+// for a type parameter, the underlying type of its constraint's first
+// type term. It returns nil if the type cannot be resolved, as for
+// predeclared types.
+func (pr *pkgReader) coreType(info typeInfo, dict *readerDict) *typeNode {
+	typ := pr.typIdx(info, dict)
+	if typ == nil {
+		return nil
+	}
+
+	switch typ.code {
+	case pkgbits.TypeNamed:
+		obj := pr.objIdx(typ.obj.idx)
+		if obj.tag != pkgbits.ObjType && obj.tag != pkgbits.ObjAlias {
+			return nil
+		}
+		return pr.coreType(obj.typ, obj.dict)
+
+	case pkgbits.TypeTypeParam:
+		if dict == nil {
+			return nil
+		}
+		i := typ.index - dict.implicits
+		if i < 0 || i >= len(dict.bounds) {
+			return nil
+		}
+		return pr.coreType(dict.bounds[i], dict)
+
+	case pkgbits.TypeInterface:
+		for _, embedded := range typ.embeddeds {
+			if core := pr.coreType(embedded, dict); core != nil && core.code != pkgbits.TypeInterface {
+				return core
+			}
+		}
+
+	case pkgbits.TypeUnion:
+		if len(typ.terms) > 0 {
+			return pr.coreType(typ.terms[0].typ, dict)
+		}
+	}
+	return typ
+}
+
+// indent returns the given statements, one per line, indented by a
+// tab.
+func indent(stmts []string) string {
+	var buf strings.Builder
+	for _, stmt := range stmts {
+		for _, line := range strings.Split(stmt, "\n") {
+			buf.WriteString("\t" + line + "\n")
+		}
+	}
+	return buf.String()
+}
+
+// @@@ Declarations
+
+// A funcDecl is a function or method declared by the package.
+type funcDecl struct {
+	obj *objNode
+	m   *methodNode // nil for functions
+}
+
+// funcDecls returns the functions and methods known to the export
+// data, keyed by their linker symbol names as listed in the private
+// root. The private root also lists the bodies of functions from other
+// packages that were inlined here. Objects that cannot be decoded are
+// omitted.
+func (pr *pkgReader) funcDecls() map[string]funcDecl {
+	decls := make(map[string]funcDecl)
+	for i := 0; i < pr.NumElems(pkgbits.SectionObj); i++ {
+		func() {
+			defer func() { recover() }()
+
+			obj := pr.objIdx(pkgbits.Index(i))
+			switch obj.tag {
+			case pkgbits.ObjFunc:
+				decls[pr.funcSymName(obj, nil)] = funcDecl{obj: obj}
+			case pkgbits.ObjType:
+				for _, m := range obj.methods {
+					decls[pr.funcSymName(obj, m)] = funcDecl{obj: obj, m: m}
+				}
+			}
+		}()
+	}
+	return decls
+}
+
+// A genericBody is the body of a generic function or method, which
+// importers compile themselves.
+type genericBody struct {
+	idx  pkgbits.Index
+	sym  string // linker symbol name, as in funcSymName
+	decl funcDecl
+}
+
+// genericBodies returns the bodies of the generic functions and methods
+// among the objects, in object order. The private root doesn't list
+// them; their extension data refers to them instead. Objects that
+// cannot be decoded are omitted.
+func (pr *pkgReader) genericBodies() []genericBody {
+	var bodies []genericBody
+	for i := 0; i < pr.NumElems(pkgbits.SectionObj); i++ {
+		func() {
+			defer func() { recover() }()
+
+			idx := pkgbits.Index(i)
+			obj := pr.objIdx(idx)
+			var decls []funcDecl
+			switch obj.tag {
+			case pkgbits.ObjFunc:
+				decls = []funcDecl{{obj: obj}}
+			case pkgbits.ObjType:
+				for _, m := range obj.methods {
+					decls = append(decls, funcDecl{obj: obj, m: m})
+				}
+			default:
+				return
+			}
+			ext := pr.objExtIdx(idx, obj)
+			for j, f := range ext.funcs {
+				if !f.extended && j < len(decls) {
+					sym := pr.funcSymName(obj, decls[j].m)
+					bodies = append(bodies, genericBody{idx: f.body, sym: sym, decl: decls[j]})
+				}
+			}
+		}()
+	}
+	return bodies
+}
+
+// bodyString returns the Go-like source of decl's body, which is the
+// specified SectionBody element, preceded by a comment giving the
+// declaration's position.
+func (pr *pkgReader) bodyString(idx pkgbits.Index, decl funcDecl) string {
+	var recv *param
+	pos, head, sig, dict := decl.obj.pos, pr.declString(decl.obj), decl.obj.sig, decl.obj.dict
+	if decl.m != nil {
+		recv, sig, dict = &decl.m.recv, decl.m.sig, methodDict(decl.obj, decl.m)
+		pos, head = decl.m.pos, pr.methodString(decl.obj, decl.m)
+	}
+	head = "// " + pos.String() + "\n" + head
+
+	stmts, ok := pr.funcBody(idx, dict, recv, sig, nil)
+	if !ok {
+		return head
+	}
+	return head + " {\n" + indent(stmts) + "}"
+}
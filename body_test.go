@@ -0,0 +1,323 @@
+package main
+
+import (
+	"go/constant"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+type stmtCode int
+
+func (c stmtCode) Marker() pkgbits.SyncMarker { return pkgbits.SyncStmt1 }
+func (c stmtCode) Value() int                 { return int(c) }
+
+type exprCode int
+
+func (c exprCode) Marker() pkgbits.SyncMarker { return pkgbits.SyncExpr }
+func (c exprCode) Value() int                 { return int(c) }
+
+type assignCode int
+
+func (c assignCode) Marker() pkgbits.SyncMarker { return pkgbits.SyncAssign }
+func (c assignCode) Value() int                 { return int(c) }
+
+// A bodyWriter writes a SectionBody element as the compiler's writer
+// does, for the statements and expressions the tests need.
+type bodyWriter struct {
+	*uirtest.Encoder
+	pos    uirtest.Pos
+	locals int
+}
+
+// newBody starts a function body with n receiver, parameter and result
+// values.
+func newBody(w *uirtest.Writer, pos uirtest.Pos, n int) *bodyWriter {
+	b := &bodyWriter{Encoder: w.NewBody(), pos: pos}
+	for range n {
+		b.addLocal()
+	}
+	b.Bool(true) // has body
+	b.Sync(pkgbits.SyncStmts)
+	return b
+}
+
+// flush ends the body's statements and writes it.
+func (b *bodyWriter) flush() pkgbits.Index {
+	b.Code(stmtCode(stmtEnd))
+	b.Sync(pkgbits.SyncStmtsEnd)
+	b.Pos(b.pos)
+	return b.Flush()
+}
+
+func (b *bodyWriter) addLocal() {
+	b.Sync(pkgbits.SyncAddLocal)
+	if b.SyncMarkers() {
+		b.Int(b.locals)
+	}
+	b.Bool(false)
+	b.locals++
+}
+
+func (b *bodyWriter) local(i int) {
+	b.Code(exprCode(exprLocal))
+	b.Sync(pkgbits.SyncUseObjLocal)
+	b.Bool(true)
+	b.Len(i)
+}
+
+func (b *bodyWriter) constant(typ uirtest.Type, val constant.Value) {
+	b.Code(exprCode(exprConst))
+	b.Pos(b.pos)
+	b.Type(typ)
+	b.Value(val)
+}
+
+func (b *bodyWriter) op(op int) {
+	b.Sync(pkgbits.SyncOp)
+	b.Len(op)
+}
+
+// exprs writes a list of values, as for a return statement or call.
+func (b *bodyWriter) exprs(exprs ...func()) {
+	b.Sync(pkgbits.SyncMultiExpr)
+	b.Bool(false)
+	b.Len(len(exprs))
+	for _, expr := range exprs {
+		expr()
+	}
+}
+
+func (b *bodyWriter) openScope() {
+	b.Sync(pkgbits.SyncOpenScope)
+	b.Pos(b.pos)
+}
+
+func (b *bodyWriter) closeScope() {
+	b.Sync(pkgbits.SyncCloseScope)
+	b.Pos(b.pos)
+	b.Sync(pkgbits.SyncCloseAnotherScope)
+}
+
+func (b *bodyWriter) noConv(typ uirtest.Type) {
+	b.Sync(pkgbits.SyncConvRTTI)
+	for range 2 {
+		b.Sync(pkgbits.SyncRType)
+		b.Bool(false)
+		b.Type(typ)
+	}
+	b.Bool(false)
+}
+
+// pushBody writes the body of List.Push:
+//
+//	return &List[E]{next: l, val: v}
+func pushBody(w *uirtest.Writer, listT uirtest.Type) pkgbits.Index {
+	b := newBody(w, w.File("example.go").Pos(27, 38), 3)
+	b.Code(stmtCode(stmtReturn))
+	b.Pos(b.pos)
+	b.exprs(func() {
+		b.Code(exprCode(exprCompLit))
+		b.Sync(pkgbits.SyncCompLit)
+		b.Pos(b.pos)
+		b.Type(listT)
+		b.Len(2)
+		for field, local := range []int{0, 1} {
+			b.Pos(b.pos)
+			b.Len(field)
+			b.local(local)
+		}
+	})
+	return b.flush()
+}
+
+// sumBody writes the body of Sum:
+//
+//	for _, x := range xs {
+//		s += x
+//	}
+//	return
+func sumBody(w *uirtest.Writer, t uirtest.Type) pkgbits.Index {
+	b := newBody(w, w.File("example.go").Pos(12, 35), 2)
+	b.Code(stmtCode(stmtFor))
+	b.Sync(pkgbits.SyncForStmt)
+	b.openScope()
+	b.Bool(true) // range
+	b.Pos(b.pos)
+	b.Len(2)
+	b.Code(assignCode(assignBlank))
+	b.Code(assignCode(assignDef))
+	b.Pos(b.pos)
+	b.LocalIdent("x")
+	b.Type(t)
+	b.addLocal()
+	b.local(0)
+	b.noConv(t)
+
+	b.Sync(pkgbits.SyncBlockStmt)
+	b.openScope()
+	b.Sync(pkgbits.SyncStmts)
+	b.Code(stmtCode(stmtAssignOp))
+	b.op(opADD)
+	b.local(1)
+	b.Pos(b.pos)
+	b.local(2)
+	b.Code(stmtCode(stmtEnd))
+	b.Sync(pkgbits.SyncStmtsEnd)
+	b.closeScope()
+	b.Bool(true) // distinct variables per iteration
+	b.Sync(pkgbits.SyncCloseAnotherScope)
+
+	b.Code(stmtCode(stmtReturn))
+	b.Pos(b.pos)
+	b.exprs()
+	return b.flush()
+}
+
+// stringBody writes the body of Person.String:
+//
+//	return "hi " + p.Name
+func stringBody(w *uirtest.Writer, stringType uirtest.Type) pkgbits.Index {
+	b := newBody(w, w.File("example.go").Pos(23, 36), 2)
+	b.Code(stmtCode(stmtReturn))
+	b.Pos(b.pos)
+	b.exprs(func() {
+		b.Code(exprCode(exprBinaryOp))
+		b.op(opADD)
+		b.constant(stringType, constant.MakeString("hi "))
+		b.Pos(b.pos)
+		b.Code(exprCode(exprFieldVal))
+		b.local(0)
+		b.Pos(b.pos)
+		b.Selector("Name")
+	})
+	return b.flush()
+}
+
+// describeBody writes the body of Describe:
+//
+//	return fmt.Sprint(p, Sum(1, 2))
+func describeBody(w *uirtest.Writer, sprint, sum *uirtest.Object, intType uirtest.Type) pkgbits.Index {
+	b := newBody(w, w.File("example.go").Pos(25, 35), 2)
+	b.Code(stmtCode(stmtReturn))
+	b.Pos(b.pos)
+	b.exprs(func() {
+		b.Code(exprCode(exprCall))
+		b.Bool(false) // method call
+		b.Bool(false) // generic function
+		b.Code(exprCode(exprGlobal))
+		b.Obj(sprint.Ref())
+		b.Pos(b.pos)
+		b.exprs(func() { b.local(0) }, func() {
+			b.Code(exprCode(exprCall))
+			b.Bool(false) // method call
+			b.Bool(true)  // generic function
+			b.Pos(b.pos)
+			b.Bool(false) // dynamic subdictionary
+			b.Obj(sum.Ref(intType))
+			b.Pos(b.pos)
+			b.exprs(func() {
+				b.constant(intType, constant.MakeInt64(1))
+			}, func() {
+				b.constant(intType, constant.MakeInt64(2))
+			})
+			b.Bool(false) // ...
+		})
+		b.Bool(false) // ...
+	})
+	return b.flush()
+}
+
+func TestBodyString(t *testing.T) {
+	pr := testReader(t, examplePackage())
+	decls := pr.funcDecls()
+	for sym, want := range map[string]string{
+		testPath + ".(*Person).String": `// example.go:23:18
+func (p *Person) String() string {
+	return "hi " + p.Name
+}`,
+		testPath + ".Describe": `// example.go:25:6
+func Describe(p *Person) string {
+	return fmt.Sprint(p, Sum[int](1, 2))
+}`,
+	} {
+		idx, ok := pr.bodies[sym]
+		if !ok {
+			t.Errorf("private root has no body for %s", sym)
+			continue
+		}
+		if got := pr.bodyString(idx, decls[sym]); got != want {
+			t.Errorf("body of %s is\n%s\nwant\n%s", sym, got, want)
+		}
+	}
+}
+
+func TestBodyOperators(t *testing.T) {
+	w := uirtest.New(pkgbits.V2, -1, testPath, "example")
+	intType := w.Basic(types.Int)
+	b := newBody(w, w.File("ops.go").Pos(1, 1), 2)
+
+	// return -(a - b) * (a + -1)
+	b.Code(stmtCode(stmtReturn))
+	b.Pos(b.pos)
+	b.exprs(func() {
+		b.Code(exprCode(exprBinaryOp))
+		b.op(opMUL)
+		b.Code(exprCode(exprUnaryOp))
+		b.op(opNEG)
+		b.Pos(b.pos)
+		b.Code(exprCode(exprBinaryOp))
+		b.op(opSUB)
+		b.local(0)
+		b.Pos(b.pos)
+		b.local(1)
+		b.Pos(b.pos)
+		b.Code(exprCode(exprBinaryOp))
+		b.op(opADD)
+		b.local(0)
+		b.Pos(b.pos)
+		b.constant(intType, constant.MakeInt64(-1))
+	})
+	idx := b.flush()
+
+	pr := testReader(t, w.Bytes())
+	sig := &signature{params: []param{{name: "a"}, {name: "b"}}}
+	stmts, ok := pr.funcBody(idx, nil, nil, sig, nil)
+	if !ok {
+		t.Fatal("no body")
+	}
+	if got, want := strings.Join(stmts, "\n"), "return -(a - b) * (a + -1)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGenericBodies(t *testing.T) {
+	pr := testReader(t, examplePackage())
+	want := map[string]string{
+		testPath + ".Sum": `// example.go:12:6
+func Sum[T Number](xs ...T) (s T) {
+	for _, x := range xs {
+		s += x
+	}
+	return
+}`,
+		testPath + ".(*List).Push": `// example.go:27:19
+func (l *List[E]) Push(v E) *List[E] {
+	return &List[E]{next: l, val: v}
+}`,
+	}
+	for _, b := range pr.genericBodies() {
+		if w, ok := want[b.sym]; ok {
+			if got := pr.bodyString(b.idx, b.decl); got != w {
+				t.Errorf("body of %s is\n%s\nwant\n%s", b.sym, got, w)
+			}
+			delete(want, b.sym)
+		}
+	}
+	for sym := range want {
+		t.Errorf("no generic body for %s", sym)
+	}
+}
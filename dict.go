@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// A readerDict is a decoded SectionObjDict element. It holds the type
+// parameters and derived types used by the object currently being
+// decoded, followed by the compiler's runtime dictionary layout. A nil
+// *readerDict is valid and describes a non-generic context.
+type readerDict struct {
+	// implicits is the number of implicit type parameters (those of
+	// an enclosing generic function), which precede the explicit ones.
+	implicits int
+
+	// tparams holds the names of the type parameters, implicit ones
+	// first, once they are known. Unknown names are empty.
+	tparams []string
+
+	// bounds holds the constraint of each explicit type parameter.
+	bounds []typeInfo
+
+	// derived holds the SectionType index of each derived type.
+	derived []pkgbits.Index
+
+	// basic reports, for each type parameter, whether its constraint
+	// is a basic interface (a method set), which lets the compiler
+	// share more code between instantiations.
+	basic []bool
+
+	// The remaining fields correspond to entries in the runtime
+	// dictionary that instantiated code is passed.
+	methodExprs []methodExprInfo
+	subdicts    []objInfo
+	rtypes      []typeInfo
+	itabs       []itabInfo
+}
+
+// A methodExprInfo is a method expression on a type parameter, such as
+// T.String.
+type methodExprInfo struct {
+	tparam int
+	method string
+}
+
+// An itabInfo is an itab needed to convert values of type typ to the
+// interface type iface.
+type itabInfo struct {
+	typ   typeInfo
+	iface typeInfo
+}
+
+// objDictIdx reads the specified object's dictionary.
+func (pr *pkgReader) objDictIdx(idx pkgbits.Index) *readerDict {
+	r := pr.newReader(pkgbits.SectionObjDict, idx, pkgbits.SyncObject1)
+
+	dict := &readerDict{implicits: r.Len()}
+	dict.bounds = make([]typeInfo, r.Len())
+	dict.tparams = make([]string, dict.implicits+len(dict.bounds))
+
+	r.dict = dict
+	for i := range dict.bounds {
+		dict.bounds[i] = r.typInfo()
+	}
+
+	dict.derived = make([]pkgbits.Index, r.Len())
+	for i := range dict.derived {
+		dict.derived[i] = r.Reloc(pkgbits.SectionType)
+		if r.Version().Has(pkgbits.DerivedInfoNeeded) {
+			r.Bool()
+		}
+	}
+
+	// Runtime dictionary information; private to the compiler.
+
+	dict.basic = make([]bool, len(dict.tparams))
+	for i := range dict.basic {
+		dict.basic[i] = r.Bool()
+	}
+
+	dict.methodExprs = make([]methodExprInfo, r.Len())
+	for i := range dict.methodExprs {
+		info := &dict.methodExprs[i]
+		info.tparam = r.Len()
+		_, info.method = r.selector()
+	}
+
+	dict.subdicts = make([]objInfo, r.Len())
+	for i := range dict.subdicts {
+		dict.subdicts[i] = r.objInfo()
+	}
+
+	dict.rtypes = make([]typeInfo, r.Len())
+	for i := range dict.rtypes {
+		dict.rtypes[i] = r.typInfo()
+	}
+
+	dict.itabs = make([]itabInfo, r.Len())
+	for i := range dict.itabs {
+		dict.itabs[i] = itabInfo{typ: r.typInfo(), iface: r.typInfo()}
+	}
+
+	return dict
+}
+
+// generic reports whether dict belongs to a generic object.
+func (dict *readerDict) generic() bool {
+	return dict != nil && len(dict.tparams) > 0
+}
+
+// tparamName returns the name of type parameter i, or $i if unknown.
+func (dict *readerDict) tparamName(i int) string {
+	if dict != nil && i < len(dict.tparams) && dict.tparams[i] != "" {
+		return dict.tparams[i]
+	}
+	return fmt.Sprintf("$%d", i)
+}
+
+// @@@ Formatting
+
+// dictLines describes the dictionary of a generic object, one entry
+// per line.
+func (pr *pkgReader) dictLines(dict *readerDict) []string {
+	var lines []string
+	add := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+
+	var params []string
+	for i := range dict.tparams {
+		s := dict.tparamName(i)
+		if i < dict.implicits {
+			s += " (implicit)"
+		} else {
+			s += " " + pr.typeString(dict.bounds[i-dict.implicits], dict)
+		}
+		if dict.basic[i] {
+			s += " (basic)"
+		}
+		params = append(params, s)
+	}
+	add("type params: %s", strings.Join(params, ", "))
+
+	for i, idx := range dict.derived {
+		add("derived[%d]: %s (SectionType[%d])", i, pr.typeString(typeInfo{idx: idx}, dict), idx)
+	}
+	for i, info := range dict.methodExprs {
+		add("method expr[%d]: %s.%s", i, dict.tparamName(info.tparam), info.method)
+	}
+	for i, info := range dict.subdicts {
+		var buf strings.Builder
+		pr.writeObj(&buf, info, dict)
+		add("subdict[%d]: %s (SectionObj[%d])", i, buf.String(), info.idx)
+	}
+	for i, info := range dict.rtypes {
+		add("rtype[%d]: %s", i, pr.typeString(info, dict))
+	}
+	for i, info := range dict.itabs {
+		add("itab[%d]: %s -> %s", i, pr.typeString(info.typ, dict), pr.typeString(info.iface, dict))
+	}
+
+	return lines
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+func TestDictLines(t *testing.T) {
+	w := uirtest.New(pkgbits.V2, 0, testPath, "example")
+	stringer := w.Interface([]uirtest.IfaceMethod{{
+		Name: "String",
+		Sig:  &uirtest.Signature{Results: []uirtest.Param{{Type: w.Basic(types.String)}}},
+	}}, nil, false)
+
+	h := w.NewObject(w.Self, "H", pkgbits.ObjFunc)
+	h.TypeParams = []uirtest.TypeParam{{Name: "V", Bound: w.Interface(nil, nil, false), Basic: true}}
+	h.Sig = &uirtest.Signature{}
+
+	// g has an implicit type parameter, as a type declared in a generic
+	// function does.
+	g := w.NewObject(w.Self, "g", pkgbits.ObjFunc)
+	g.Implicits = 1
+	g.TypeParams = []uirtest.TypeParam{{Name: "U", Bound: stringer, Basic: true}}
+	g.Sig = &uirtest.Signature{}
+	tu := w.TypeParam(1)
+	u := g.Derive(tu)
+	su := w.Slice(u)
+	g.Derive(su)
+	g.MethodExprs = []uirtest.MethodExpr{{TypeParam: 1, Method: "String"}}
+	g.Subdicts = []uirtest.ObjRef{h.Ref(u)}
+	g.RTypes = []uirtest.Type{{Idx: 1, Derived: true}}
+	g.Itabs = [][2]uirtest.Type{{u, stringer}}
+	h.Flush()
+	g.Flush()
+
+	pr := testReader(t, w.Bytes())
+	obj := pr.objIdx(g.Idx)
+	want := []string{
+		"type params: $0 (implicit), U interface{String() string} (basic)",
+		fmt.Sprintf("derived[0]: U (SectionType[%d])", tu.Idx),
+		fmt.Sprintf("derived[1]: []U (SectionType[%d])", su.Idx),
+		"method expr[0]: U.String",
+		fmt.Sprintf("subdict[0]: H[U] (SectionObj[%d])", h.Idx),
+		"rtype[0]: []U",
+		"itab[0]: U -> interface{String() string}",
+	}
+	if got := pr.dictLines(obj.dict); !slices.Equal(got, want) {
+		t.Errorf("dictionary of g is\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if !obj.dict.generic() {
+		t.Errorf("g is not generic")
+	}
+}
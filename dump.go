@@ -0,0 +1,178 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// dumpBytesPerLine is the number of bytes shown on each line of a dump.
+const dumpBytesPerLine = 8
+
+// runDump implements the dump command: it prints the bytes of one
+// element, annotated with the values that decoding it reads from them.
+func runDump(args []string, cfg *importCfg) (err error) {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	element := flags.String("element", "", "Dump `elem`, given as section:index (such as SectionObj:12 or Obj:12)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s dump -element elem <file|package>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Prints the bytes of an element with the values decoded from them\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 || *element == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	inputs, err := resolveInputs(flags.Args(), cfg)
+	if err != nil {
+		return err
+	}
+	if len(inputs) != 1 {
+		return fmt.Errorf("%s matches %d packages, want one", flags.Arg(0), len(inputs))
+	}
+	f, err := readExportFile(inputs[0])
+	if err != nil {
+		return err
+	}
+	decoder, err := f.newDecoder(cfg)
+	if err != nil {
+		return err
+	}
+	e, ok, err := parseElemSpec(decoder, *element)
+	if !ok {
+		err = fmt.Errorf("bad element %q: want section:index, such as SectionObj:12", *element)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = decodeError(r)
+		}
+	}()
+
+	data := decoder.DataIdx(e.k, e.idx)
+	fmt.Printf("%v in %s: %d bytes, element %d of %d\n", e, f, len(data), decoder.AbsIdx(e.k, e.idx), decoder.TotalElems())
+
+	if e.k == pkgbits.SectionString {
+		// Strings are stored as raw bytes, without a reference table.
+		fmt.Println()
+		writeDumpBytes(os.Stdout, data, 0, len(data), "string", strconv.Quote(data))
+		return nil
+	}
+
+	trace, errs := traceElem(f, decoder, cfg, e)
+	if trace == nil {
+		fmt.Println()
+		writeDumpBytes(os.Stdout, data, 0, len(data), "unread", "")
+	} else {
+		writeDump(os.Stdout, data, trace)
+	}
+	for _, err := range errs {
+		fmt.Printf("\nerror: %v\n", err)
+	}
+	return nil
+}
+
+// traceElem decodes the export data with tracing enabled for element e.
+// It returns the trace that read the furthest into e's data, or nil if
+// e was never read, along with any errors decoding e.
+func traceElem(f *exportFile, decoder *pkgbits.PkgDecoder, cfg *importCfg, e elemKey) (best *pkgbits.ElemTrace, errs []error) {
+	var traces []*pkgbits.ElemTrace
+	decoder.TraceElem(e.k, e.idx, &traces)
+
+	// Validation decodes every element the way importers do, including
+	// the function bodies that need their declarations to be read.
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errs = append(errs, decodeError(r))
+			}
+		}()
+		pr := f.newPkgReader(decoder, cfg)
+		verrs, _ := pr.validateElems()
+		prefix := fmt.Sprintf("%v[%d]:", e.k, e.idx)
+		for _, err := range verrs {
+			if strings.HasPrefix(err.Error(), prefix) {
+				errs = append(errs, err)
+			}
+		}
+	}()
+
+	end := func(t *pkgbits.ElemTrace) int {
+		if len(t.Entries) == 0 {
+			return t.RelocsEnd
+		}
+		return t.Entries[len(t.Entries)-1].End
+	}
+	for _, t := range traces {
+		if best == nil || end(t) > end(best) {
+			best = t
+		}
+	}
+	return best, errs
+}
+
+// writeDump writes the bytes of an element's data, split into its
+// reference table and its own data, with the values that trace records
+// reading from them.
+func writeDump(w io.Writer, data string, trace *pkgbits.ElemTrace) {
+	tableEnd := trace.RelocsEnd
+	if tableEnd == 0 {
+		// Decoding failed within the reference table, which always
+		// has at least its length.
+		tableEnd = len(data)
+	}
+	n := 0
+	for n < len(trace.Entries) && trace.Entries[n].Start < tableEnd {
+		n++
+	}
+	fmt.Fprintf(w, "\nReference table (bytes 0-%d):\n", tableEnd)
+	writeDumpEntries(w, data, trace.Entries[:n], 0, tableEnd)
+	if tableEnd < len(data) {
+		fmt.Fprintf(w, "\nData (bytes %d-%d):\n", tableEnd, len(data))
+		writeDumpEntries(w, data, trace.Entries[n:], tableEnd, len(data))
+	}
+}
+
+// writeDumpEntries writes data[start:end] with the values of entries,
+// which are in order and within that range. Bytes that no entry covers
+// are shown as unread.
+func writeDumpEntries(w io.Writer, data string, entries []pkgbits.TraceEntry, start, end int) {
+	pos := start
+	for _, ent := range entries {
+		if ent.Start > pos {
+			writeDumpBytes(w, data, pos, ent.Start, "unread", "")
+		}
+		value := ent.Value
+		if len(ent.Notes) > 0 {
+			value += " (" + strings.Join(ent.Notes, "; ") + ")"
+		}
+		writeDumpBytes(w, data, ent.Start, ent.End, ent.Kind, value)
+		pos = ent.End
+	}
+	if pos < end {
+		writeDumpBytes(w, data, pos, end, "unread", "")
+	}
+}
+
+// writeDumpBytes writes data[start:end] in hex, prefixed by its offset,
+// with kind and value on its first line.
+func writeDumpBytes(w io.Writer, data string, start, end int, kind, value string) {
+	for off := start; off < end; off += dumpBytesPerLine {
+		b := []byte(data[off:min(off+dumpBytesPerLine, end)])
+		hex := fmt.Sprintf("% x", b)
+		if off == start {
+			fmt.Fprintf(w, "  %6d  %-*s  %-8s %s\n", off, 3*dumpBytesPerLine-1, hex, kind, value)
+		} else {
+			fmt.Fprintf(w, "  %6d  %s\n", off, hex)
+		}
+	}
+}
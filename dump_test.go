@@ -0,0 +1,61 @@
+package main
+
+import (
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+func TestDump(t *testing.T) {
+	w := uirtest.New(pkgbits.V2, 0, testPath, "example")
+	x := w.NewObject(w.Self, "x", pkgbits.ObjVar)
+	x.Type = w.Basic(types.Int)
+	x.Flush()
+	w.Export(x)
+	f := rawFile(w.Bytes())
+	decoder, err := f.newDecoder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := elemKey{pkgbits.SectionObj, x.Idx}
+	trace, errs := traceElem(f, decoder, nil, e)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if trace == nil {
+		t.Fatalf("%v was never read", e)
+	}
+	var b strings.Builder
+	writeDump(&b, decoder.DataIdx(e.k, e.idx), trace)
+	if got := b.String(); got != wantDump {
+		t.Errorf("dump of %v is%s\nwant%s", e, got, wantDump)
+	}
+}
+
+const wantDump = `
+Reference table (bytes 0-13):
+       0  08 00                    sync     Relocs
+       2  04 00                    sync     Uint64
+       4  01                       uvarint  1 (reference table length)
+       5  09 00                    sync     Reloc
+       7  04 00                    sync     Uint64
+       9  05                       uvarint  5 (reference 0 section: SectionType)
+      10  04 00                    sync     Uint64
+      12  00                       uvarint  0 (reference 0 index)
+
+Data (bytes 13-30):
+      13  0f 00                    sync     Object1
+      15  0c 00                    sync     Pos
+      17  02 00                    sync     Bool
+      19  00                       bool     false
+      20  13 00                    sync     Type
+      22  02 00                    sync     Bool
+      24  00                       bool     false
+      25  0a 00                    sync     UseReloc
+      27  04 00                    sync     Uint64
+      29  00                       uvarint  0 (reference 0: SectionType:0)
+`
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// runExplain implements the explain command: it walks the header of the
+// export data field by field, in the order pkgbits.ParsePkgDecoder
+// reads it, with the file offset and bytes of each field.
+func runExplain(args []string, cfg *importCfg) error {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s explain <file|package>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Walks the header of the export data, showing the offset and bytes of each field\n")
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	inputs, err := resolveInputs(flags.Args(), cfg)
+	if err != nil {
+		return err
+	}
+	if len(inputs) != 1 {
+		return fmt.Errorf("%s matches %d packages, want one", flags.Arg(0), len(inputs))
+	}
+	f, err := readExportFile(inputs[0])
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(f.filename)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	// Show offsets within the file, unless the export data isn't in it,
+	// as for the members of a thin archive.
+	if f.importPath != "" {
+		fmt.Printf("%s: ", f.importPath)
+	}
+	data, start := string(f.uirData), 0
+	if i := bytes.Index(raw, f.uirData); i >= 0 {
+		data, start = string(raw), i
+		fmt.Printf("Export data at offset %d of %s (%v):\n\n", start, f.filename, f.container)
+	} else {
+		fmt.Printf("Export data stored outside %s; offsets are within the export data:\n\n", f.filename)
+	}
+
+	// The decoder is only used to check the fingerprint; the header is
+	// walked even if it is malformed, up to the problem.
+	decoder, parseErr := f.newDecoder(cfg)
+	h := &headerWalker{w: os.Stdout, data: data[:start+len(f.uirData)], off: start}
+	h.walk(decoder)
+	return parseErr
+}
+
+// A headerWalker writes the fields of a header in data, starting at
+// off.
+type headerWalker struct {
+	w    io.Writer
+	data string
+	off  int
+}
+
+// field writes the next n bytes as a field of the given type and
+// description, and reports whether there were n bytes.
+func (h *headerWalker) field(n int, typ, desc string) bool {
+	if len(h.data)-h.off < n {
+		writeDumpBytes(h.w, h.data, h.off, len(h.data), typ, fmt.Sprintf("truncated: %s needs %d bytes", desc, n))
+		h.note(len(h.data), "(end of export data)")
+		return false
+	}
+	writeDumpBytes(h.w, h.data, h.off, h.off+n, typ, desc)
+	h.off += n
+	return true
+}
+
+// note writes text in the description column, at offset off.
+func (h *headerWalker) note(off int, text string) {
+	fmt.Fprintf(h.w, "  %6d  %*s%s\n", off, 3*dumpBytesPerLine-1+2+8+1, "", text)
+}
+
+// walk writes the header fields, and the fingerprint that follows the
+// element data. decoder, if non-nil, is the parsed export data.
+func (h *headerWalker) walk(decoder *pkgbits.PkgDecoder) {
+	if !h.field(1, "byte", "'u': the export data is Unified IR") {
+		return
+	}
+
+	fields, err := pkgbits.HeaderFields(h.data[h.off:])
+
+	// ends holds the cumulative element count at the end of each
+	// section.
+	var ends [pkgbits.SectionBody + 1]uint32
+	var elemEnds []uint32
+	var prevEnd uint32
+	for _, f := range fields {
+		var desc string
+		switch f.Name {
+		case "version":
+			v := pkgbits.Version(f.Value)
+			desc = fmt.Sprintf("Version = %d (V%d)", v, v)
+		case "flags":
+			desc = fmt.Sprintf("Flags = %#x: flagSyncMarkers clear, elements have no sync markers", f.Value)
+			if f.Value&pkgbits.FlagSyncMarkers != 0 {
+				desc = fmt.Sprintf("Flags = %#x: flagSyncMarkers set, elements have sync markers", f.Value)
+			}
+			if other := f.Value &^ pkgbits.FlagSyncMarkers; other != 0 {
+				desc += fmt.Sprintf(", unknown bits %#x", other)
+			}
+		case "elemEndsEnds":
+			k := f.Index
+			ends[k] = f.Value
+			var prev uint32
+			if k > 0 {
+				prev = ends[k-1]
+			}
+			desc = fmt.Sprintf("ElemEndsEnds[%v] = %d", pkgbits.SectionKind(k), ends[k])
+			if ends[k] >= prev {
+				desc += fmt.Sprintf(": %d elements", ends[k]-prev)
+			} else {
+				desc += fmt.Sprintf(": bad section count, less than %d", prev)
+			}
+		case "elemEnds":
+			i := f.Index
+			k := pkgbits.SectionKind(0)
+			for int(ends[k]) <= i {
+				k++
+			}
+			first := 0
+			if k > 0 {
+				first = int(ends[k-1])
+			}
+			elemEnds = append(elemEnds, f.Value)
+			desc = fmt.Sprintf("ElemEnds[%d] = %d: %v:%d", i, f.Value, k, i-first)
+			if f.Value >= prevEnd {
+				desc += fmt.Sprintf(", %d bytes", f.Value-prevEnd)
+				prevEnd = f.Value
+			} else {
+				desc += fmt.Sprintf(", bad end before the previous element's %d", prevEnd)
+			}
+		}
+		h.field(4, "uint32", desc)
+		if f.Name == "version" && !pkgbits.Version(f.Value).Has(pkgbits.Flags) {
+			h.note(h.off, "(no Flags before V1: elements have no sync markers)")
+		}
+	}
+	var truncated *pkgbits.TruncatedHeaderError
+	if errors.As(err, &truncated) {
+		typ := "uint32"
+		if truncated.Need > 4 {
+			typ = "uint32s"
+		}
+		h.field(truncated.Need, typ, truncated.Field)
+		return
+	}
+
+	total := len(elemEnds)
+	dataLen := 0
+	if total > 0 {
+		dataLen = int(elemEnds[total-1])
+	}
+	dataStart := h.off
+	writeDumpBytes(h.w, h.data, dataStart, min(dataStart+dumpBytesPerLine, dataStart+dataLen, len(h.data)), "bytes",
+		fmt.Sprintf("ElemData: %d bytes of element bitstreams, up to offset %d", dataLen, dataStart+dataLen))
+	if dataLen > dumpBytesPerLine {
+		fmt.Fprintf(h.w, "  %6s\n", "...")
+	}
+	h.off = dataStart + dataLen
+	if h.off > len(h.data) {
+		h.note(len(h.data), fmt.Sprintf("(end of export data, %d bytes short)", h.off-len(h.data)))
+		return
+	}
+
+	desc := "Fingerprint"
+	if len(h.data)-h.off >= 8 {
+		desc = fmt.Sprintf("Fingerprint = %x", h.data[h.off:h.off+8])
+	}
+	if decoder != nil {
+		if fp := decoder.ComputeFingerprint(); fp == decoder.Fingerprint() {
+			desc += " (verified)"
+		} else {
+			desc += fmt.Sprintf(" (MISMATCH: data hashes to %x)", fp)
+		}
+	}
+	if !h.field(8, "[8]byte", desc) {
+		return
+	}
+	if n := len(h.data) - h.off; n > 0 {
+		writeDumpBytes(h.w, h.data, h.off, len(h.data), "bytes", fmt.Sprintf("%d unexpected bytes after the fingerprint", n))
+	} else {
+		h.note(h.off, "(end of export data)")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+func TestExplainHeader(t *testing.T) {
+	w := uirtest.New(pkgbits.V2, 0, testPath, "example")
+	w.Pkg("fmt", "fmt")
+	data := string(w.Bytes())
+	decoder, err := rawFile([]byte(data)).newDecoder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flip an unknown flag bit; the walker doesn't need a decoder.
+	flagged := data[:5] + "\x03" + data[6:]
+
+	tests := []struct {
+		name    string
+		data    string
+		decoder *pkgbits.PkgDecoder
+		want    []string
+	}{
+		{"whole", data, decoder, []string{
+			"Version = 2 (V2)",
+			"Flags = 0x1: flagSyncMarkers set, elements have sync markers",
+			"ElemEndsEnds[SectionPkg] = 7: 2 elements",
+			"ElemEnds[0] = ",
+			"ElemData: ",
+			"(verified)",
+			"(end of export data)\n",
+		}},
+		{"unknown flags", flagged, nil, []string{
+			"Flags = 0x3: flagSyncMarkers set, elements have sync markers, unknown bits 0x2",
+		}},
+		{"truncated", data[:20], nil, []string{
+			"ElemEndsEnds[SectionMeta] = 5: 2 elements",
+			"truncated: ",
+			"      20  ",
+		}},
+		{"truncated data", data[:len(data)-20], nil, []string{
+			"bytes short)",
+		}},
+	}
+	for _, test := range tests {
+		var b strings.Builder
+		h := &headerWalker{w: &b, data: test.data}
+		h.walk(test.decoder)
+		for _, want := range test.want {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("%s: header lacks %q:\n%s", test.name, want, b.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// An input is a file to decode, named on the command line or found by
+// import path.
+type input struct {
+	filename string
+
+	// importPath is the import path the file was found by, and
+	// pathSource how: "importcfg" or "go list". Both are empty for
+	// files named directly.
+	importPath string
+	pathSource string
+}
+
+// String returns the name to report the input by.
+func (in input) String() string {
+	if in.importPath != "" {
+		return in.importPath
+	}
+	return in.filename
+}
+
+// resolveInputs returns the files named by the command-line arguments
+// args. Arguments naming existing files are used as they are; others
+// are import paths, looked up in cfg if it is non-nil, or patterns,
+// whose export data go list finds.
+func resolveInputs(args []string, cfg *importCfg) ([]input, error) {
+	var inputs []input
+	for _, arg := range args {
+		if fi, err := os.Stat(arg); err == nil && !fi.IsDir() {
+			inputs = append(inputs, input{filename: arg})
+			continue
+		}
+		if file, ok := cfg.lookup(arg); ok {
+			inputs = append(inputs, input{filename: file, importPath: cfg.resolve(arg), pathSource: "importcfg"})
+			continue
+		}
+		pkgs, err := goListExport(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range pkgs {
+			inputs = append(inputs, input{filename: pkg.Export, importPath: pkg.ImportPath, pathSource: "go list"})
+		}
+	}
+	return inputs, nil
+}
+
+// A listedPackage is the part of a package's "go list -json" output
+// that we use.
+type listedPackage struct {
+	ImportPath string
+	Export     string // export data file, with -export
+	Error      *struct{ Err string }
+}
+
+// goListExport runs "go list -export" on pattern, building the
+// matching packages if needed, and returns them with their export data
+// files.
+func goListExport(pattern string) ([]listedPackage, error) {
+	cmd := exec.Command("go", "list", "-e", "-export", "-json=ImportPath,Export,Error", "--", pattern)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("go list %s: %s", pattern, msg)
+		}
+		return nil, fmt.Errorf("go list %s: %w", pattern, err)
+	}
+
+	var pkgs, noExport []listedPackage
+	dec := json.NewDecoder(&stdout)
+	for {
+		var pkg listedPackage
+		if err := dec.Decode(&pkg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list %s: %w", pattern, err)
+		}
+		switch {
+		case pkg.Error != nil:
+			return nil, fmt.Errorf("go list %s: %s", pattern, pkg.Error.Err)
+		case pkg.Export == "":
+			// Such as unsafe, or packages with only test files.
+			noExport = append(noExport, pkg)
+		default:
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	switch {
+	case len(pkgs) == 0 && len(noExport) == 1:
+		return nil, fmt.Errorf("go list %s: package %s has no export data", pattern, noExport[0].ImportPath)
+	case len(pkgs) == 0:
+		return nil, fmt.Errorf("go list %s: no packages with export data", pattern)
+	}
+	for _, pkg := range noExport {
+		fmt.Fprintf(os.Stderr, "skipping %s: no export data\n", pkg.ImportPath)
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResolveInputs(t *testing.T) {
+	filename := writeTemp(t, "example.a", examplePackage())
+	cfg := &importCfg{
+		packageFile: map[string]string{"fmt": "/cache/fmt.a"},
+		importMap:   map[string]string{"vendor/fmt": "fmt"},
+	}
+
+	// Neither argument needs go list.
+	got, err := resolveInputs([]string{filename, "vendor/fmt"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []input{
+		{filename: filename},
+		{filename: "/cache/fmt.a", importPath: "fmt", pathSource: "importcfg"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("inputs are %+v, want %+v", got, want)
+	}
+	if got[0].String() != filename || got[1].String() != "fmt" {
+		t.Errorf("inputs are named %v and %v", got[0], got[1])
+	}
+
+	// unsafe is listed, but has no export data.
+	if _, err := resolveInputs([]string{"unsafe"}, nil); err == nil || !strings.Contains(err.Error(), "package unsafe has no export data") {
+		t.Errorf("resolving unsafe gives error %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// runGraph implements the graph command: it writes the graph of
+// references between elements, as recorded in their reference tables,
+// in Graphviz DOT format.
+func runGraph(args []string, cfg *importCfg) error {
+	flags := flag.NewFlagSet("graph", flag.ExitOnError)
+	from := flags.String("from", "", "Only show elements reachable from `elem`, given as section:index (such as SectionObj:12 or Obj:12) or an object name")
+	depth := flags.Int("depth", 0, "With -from, follow at most `n` references (0 = no limit)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s graph [-from elem] [-depth n] <file|package>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Writes the element reference graph in Graphviz DOT format\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	inputs, err := resolveInputs(flags.Args(), cfg)
+	if err != nil {
+		return err
+	}
+	if len(inputs) != 1 {
+		return fmt.Errorf("%s matches %d packages, want one", flags.Arg(0), len(inputs))
+	}
+	f, err := readExportFile(inputs[0])
+	if err != nil {
+		return err
+	}
+	decoder, err := f.newDecoder(cfg)
+	if err != nil {
+		return err
+	}
+
+	g := &elemGraph{pd: decoder, edges: make(map[elemKey][]elemKey)}
+	var roots []elemKey
+	if *from != "" {
+		start, err := g.findElem(*from)
+		if err != nil {
+			return err
+		}
+		roots = []elemKey{start}
+	} else {
+		for k := pkgbits.SectionString; k <= pkgbits.SectionBody; k++ {
+			for i := range decoder.NumElems(k) {
+				roots = append(roots, elemKey{k, pkgbits.Index(i)})
+			}
+		}
+	}
+
+	g.walk(roots, *depth)
+	g.writeDOT(os.Stdout, f.String())
+	return nil
+}
+
+// An elemGraph is the graph of references between elements, built by
+// walk.
+type elemGraph struct {
+	pd *pkgbits.PkgDecoder
+
+	nodes []elemKey             // in the order visited
+	edges map[elemKey][]elemKey // the references of each node in nodes
+}
+
+// walk adds the elements reachable from roots to the graph, following
+// at most depth references if depth is positive.
+func (g *elemGraph) walk(roots []elemKey, depth int) {
+	seen := make(map[elemKey]bool)
+	queue := roots
+	for _, root := range roots {
+		seen[root] = true
+	}
+	for level := 0; len(queue) > 0; level++ {
+		var next []elemKey
+		for _, e := range queue {
+			g.nodes = append(g.nodes, e)
+			if depth > 0 && level >= depth {
+				continue
+			}
+			refs, err := elemRefs(g.pd, e)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v[%d]: %v\n", e.k, e.idx, err)
+				continue
+			}
+			g.edges[e] = refs
+			for _, ref := range refs {
+				if !seen[ref] {
+					seen[ref] = true
+					next = append(next, ref)
+				}
+			}
+		}
+		queue = next
+	}
+}
+
+// elemRefs returns the distinct elements referenced by e's reference
+// table, in table order.
+func elemRefs(pd *pkgbits.PkgDecoder, e elemKey) (refs []elemKey, err error) {
+	if e.k == pkgbits.SectionString {
+		// Strings are stored without a reference table.
+		return nil, nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cannot read reference table: %v", r)
+		}
+	}()
+
+	r := pd.NewDecoderRaw(e.k, e.idx)
+	seen := make(map[elemKey]bool)
+	for _, ent := range r.Relocs {
+		ref := elemKey{ent.Kind, ent.Idx}
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// findElem returns the element named by spec: "section:index", with or
+// without the "Section" prefix, or the name of an object, optionally
+// qualified by its package path.
+func (g *elemGraph) findElem(spec string) (elemKey, error) {
+	if e, ok, err := parseElemSpec(g.pd, spec); ok {
+		return e, err
+	}
+	for i := range g.pd.NumElems(pkgbits.SectionObj) {
+		path, name, _ := g.pd.PeekObj(pkgbits.Index(i))
+		if spec == name || spec == path+"."+name {
+			return elemKey{pkgbits.SectionObj, pkgbits.Index(i)}, nil
+		}
+	}
+	return elemKey{}, fmt.Errorf("no element or object named %q", spec)
+}
+
+// parseElemSpec parses an element given as "section:index", such as
+// "SectionObj:12" or "Obj:12", and checks that it exists. It reports
+// whether spec has that form.
+func parseElemSpec(pd *pkgbits.PkgDecoder, spec string) (e elemKey, ok bool, err error) {
+	section, index, ok := strings.Cut(spec, ":")
+	if !ok {
+		return elemKey{}, false, nil
+	}
+	k, found := pkgbits.SectionKind(-1), false
+	for k = pkgbits.SectionString; k <= pkgbits.SectionBody; k++ {
+		if name := k.String(); strings.EqualFold(section, name) || strings.EqualFold(section, strings.TrimPrefix(name, "Section")) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return elemKey{}, true, fmt.Errorf("unknown section %q", section)
+	}
+	idx, err := strconv.Atoi(index)
+	if err != nil {
+		return elemKey{}, true, fmt.Errorf("bad element index %q", index)
+	}
+	if n := pd.NumElems(k); idx < 0 || idx >= n {
+		return elemKey{}, true, fmt.Errorf("%s is out of range: %v has %d elements", spec, k, n)
+	}
+	return elemKey{k, pkgbits.Index(idx)}, true, nil
+}
+
+// label describes e for its graph node.
+func (g *elemGraph) label(e elemKey) (label string) {
+	label = e.String()
+	defer func() {
+		if r := recover(); r != nil {
+			label += "\n(unreadable)"
+		}
+	}()
+
+	switch e.k {
+	case pkgbits.SectionString:
+		s := g.pd.StringIdx(e.idx)
+		if len(s) > 32 {
+			s = s[:29] + "..."
+		}
+		label += "\n" + strconv.Quote(s)
+	case pkgbits.SectionPkg:
+		if path := g.pd.PeekPkgPath(e.idx); path != "" {
+			label += "\n" + path
+		}
+	case pkgbits.SectionName, pkgbits.SectionObj, pkgbits.SectionObjExt, pkgbits.SectionObjDict:
+		_, name, tag := g.pd.PeekObj(e.idx)
+		label += fmt.Sprintf("\n%s %s", objTagName(tag), name)
+	case pkgbits.SectionMeta:
+		if e.idx == pkgbits.PublicRootIdx {
+			label += "\npublic root"
+		} else if e.idx == pkgbits.PrivateRootIdx {
+			label += "\nprivate root"
+		}
+	}
+	return label
+}
+
+// writeDOT writes the graph in Graphviz DOT format, sorted by section
+// and index so that the same export data always gives the same output.
+func (g *elemGraph) writeDOT(w io.Writer, name string) {
+	nodes := append([]elemKey{}, g.nodes...)
+	for _, refs := range g.edges {
+		nodes = append(nodes, refs...)
+	}
+	slices.SortFunc(nodes, func(a, b elemKey) int {
+		return cmp.Or(cmp.Compare(a.k, b.k), cmp.Compare(a.idx, b.idx))
+	})
+	nodes = slices.Compact(nodes)
+
+	id := func(e elemKey) string { return dotQuote(e.String()) }
+
+	fmt.Fprintf(w, "digraph %s {\n", dotQuote(name))
+	fmt.Fprintf(w, "\tnode [shape=box, fontname=\"monospace\"];\n")
+	for _, e := range nodes {
+		fmt.Fprintf(w, "\t%s [label=%s];\n", id(e), dotQuote(g.label(e)))
+	}
+	for _, e := range nodes {
+		for _, ref := range g.edges[e] {
+			fmt.Fprintf(w, "\t%s -> %s;\n", id(e), id(ref))
+		}
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+// dotQuote returns s as a DOT string, in which "\n" is a line break.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+func TestGraph(t *testing.T) {
+	w := uirtest.New(pkgbits.V2, -1, testPath, "example")
+	x := w.NewObject(w.Self, "x", pkgbits.ObjVar)
+	x.Type = w.Slice(w.Basic(types.Int))
+	x.Flush()
+	w.Export(x)
+	f := rawFile(w.Bytes())
+	decoder, err := f.newDecoder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &elemGraph{pd: decoder, edges: make(map[elemKey][]elemKey)}
+	start, err := g.findElem(testPath + ".x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (elemKey{pkgbits.SectionObj, x.Idx}); start != want {
+		t.Fatalf("found %v, want %v", start, want)
+	}
+	g.walk([]elemKey{start}, 1)
+
+	typ := elemKey{pkgbits.SectionType, x.Type.Idx}
+	if !slices.Contains(g.edges[start], typ) {
+		t.Errorf("x references %v, want %v among them", g.edges[start], typ)
+	}
+	if _, ok := g.edges[typ]; ok {
+		t.Errorf("walk went past depth 1 to the references of %v", typ)
+	}
+
+	var b strings.Builder
+	g.writeDOT(&b, "x.a")
+	dot := b.String()
+	node := func(e elemKey) string { return fmt.Sprintf("%v:%d", e.k, e.idx) }
+	for _, want := range []string{
+		"digraph \"x.a\" {\n",
+		fmt.Sprintf("\t\"%s\" [label=\"%s\\nVar x\"];\n", node(start), node(start)),
+		fmt.Sprintf("\t\"%s\" -> \"%s\";\n", node(start), node(typ)),
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("graph lacks %q:\n%s", want, dot)
+		}
+	}
+}
+
+func TestParseElemSpec(t *testing.T) {
+	decoder, err := rawFile(examplePackage()).newDecoder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		spec string
+		want elemKey
+		ok   bool
+		err  string
+	}{
+		{"SectionObj:1", elemKey{pkgbits.SectionObj, 1}, true, ""},
+		{"obj:1", elemKey{pkgbits.SectionObj, 1}, true, ""},
+		{"Meta:0", elemKey{pkgbits.SectionMeta, 0}, true, ""},
+		{"Sum", elemKey{}, false, ""},
+		{"Frob:1", elemKey{}, true, `unknown section "Frob"`},
+		{"Obj:x", elemKey{}, true, `bad element index "x"`},
+		{"Obj:1000", elemKey{}, true, "out of range"},
+	}
+	for _, test := range tests {
+		e, ok, err := parseElemSpec(decoder, test.spec)
+		if ok != test.ok || (err == nil) != (test.err == "") || err != nil && !strings.Contains(err.Error(), test.err) {
+			t.Errorf("parseElemSpec(%q) = %v, %v, %v, want %v, %v, error %q", test.spec, e, ok, err, test.want, test.ok, test.err)
+			continue
+		}
+		if err == nil && e != test.want {
+			t.Errorf("parseElemSpec(%q) = %v, want %v", test.spec, e, test.want)
+		}
+	}
+}
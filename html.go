@@ -0,0 +1,308 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// writeHTML writes rep as a self-contained HTML page, with an anchor
+// for each element shown and links for the references between them.
+// References to elements that aren't shown, such as those cut off by
+// -limit, are written as plain text.
+func (rep *report) writeHTML(w io.Writer) error {
+	anchors := rep.anchors()
+	href := func(ref string) string {
+		if id := elemAnchor(ref); anchors[id] {
+			return "#" + id
+		}
+		return ""
+	}
+	page := template.Must(htmlReport.Clone()).Funcs(template.FuncMap{
+		"href":    href,
+		"linkify": func(s string) template.HTML { return linkify(s, href) },
+	})
+	return page.Execute(w, rep)
+}
+
+// elemRefRE matches element references in report text: "SectionObj:12"
+// as in refs, or "SectionBody[3]" as in extension data descriptions.
+var elemRefRE = regexp.MustCompile(`\bSection[A-Za-z]+(?::\d+|\[\d+\])`)
+
+// elemAnchor returns the anchor id of the element shown for an element
+// reference, or "" if ref isn't one. Name, extension and dictionary
+// elements are shown with the object of the same index. Ids have no
+// colon, which html/template would take for a URL scheme in links.
+func elemAnchor(ref string) string {
+	name, index, ok := strings.Cut(strings.TrimSuffix(strings.Replace(ref, "[", ":", 1), "]"), ":")
+	if !ok {
+		return ""
+	}
+	idx, err := strconv.Atoi(index)
+	if err != nil {
+		return ""
+	}
+	switch name {
+	case "SectionMeta":
+		if idx == int(pkgbits.PublicRootIdx) {
+			return "public-root"
+		}
+		return "private-root"
+	case "SectionName", "SectionObjExt", "SectionObjDict":
+		name = "SectionObj"
+	}
+	return name + "-" + index
+}
+
+// anchors returns the anchor ids of the elements shown in rep.
+func (rep *report) anchors() map[string]bool {
+	ids := map[string]bool{"public-root": true, "private-root": true}
+	add := func(k pkgbits.SectionKind, idx int) {
+		ids[elemAnchor(elemKey{k, pkgbits.Index(idx)}.String())] = true
+	}
+	for _, s := range rep.Strings {
+		add(pkgbits.SectionString, s.Index)
+	}
+	for _, b := range rep.PosBases {
+		add(pkgbits.SectionPosBase, b.Index)
+	}
+	for _, p := range rep.Packages {
+		add(pkgbits.SectionPkg, p.Index)
+	}
+	for _, t := range rep.Types {
+		add(pkgbits.SectionType, t.Index)
+	}
+	for _, o := range rep.Objects {
+		add(pkgbits.SectionObj, o.Index)
+	}
+	for _, b := range rep.PrivateRoot.Bodies {
+		add(pkgbits.SectionBody, b.BodyIndex)
+	}
+	for _, b := range rep.GenericBodies.Bodies {
+		add(pkgbits.SectionBody, b.BodyIndex)
+	}
+	return ids
+}
+
+// linkify escapes s for HTML, turning the element references in it
+// into links where href has a target for them.
+func linkify(s string, href func(ref string) string) template.HTML {
+	var buf strings.Builder
+	last := 0
+	for _, m := range elemRefRE.FindAllStringIndex(s, -1) {
+		buf.WriteString(template.HTMLEscapeString(s[last:m[0]]))
+		ref := s[m[0]:m[1]]
+		if target := href(ref); target != "" {
+			buf.WriteString(`<a href="` + template.HTMLEscapeString(target) + `">` + template.HTMLEscapeString(ref) + `</a>`)
+		} else {
+			buf.WriteString(template.HTMLEscapeString(ref))
+		}
+		last = m[1]
+	}
+	buf.WriteString(template.HTMLEscapeString(s[last:]))
+	return template.HTML(buf.String())
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"href":    func(string) string { return "" },
+	"linkify": func(s string) template.HTML { return template.HTML(template.HTMLEscapeString(s)) },
+	"more":    func(shown, total int) int { return total - shown },
+	"quote":   strconv.Quote,
+	"title": func(rep *report) string {
+		if rep.Build.ImportPath != "" {
+			return rep.Build.ImportPath
+		}
+		return rep.Build.File
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{title .}} — Unified IR</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 70em; padding: 0 1em; color: #222; }
+h1 { border-bottom: 2px solid #375eab; }
+h2 { background: #e0ebf5; padding: 0.2em 0.5em; margin-top: 2em; }
+code, pre, td.mono { font-family: monospace; }
+pre { background: #f8f8f8; padding: 0.5em; overflow-x: auto; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+td.num { text-align: right; }
+nav a { margin-right: 1em; }
+.elem { border-left: 3px solid #ccc; margin: 0.8em 0; padding-left: 0.8em; }
+.elem:target, tr:target { background: #ffffe0; }
+.refs, .note { color: #666; font-size: 90%; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>{{title .}}</h1>
+<nav>
+<a href="#build">Build</a>
+<a href="#format">Format</a>
+<a href="#sections">Sections</a>
+<a href="#strings">Strings</a>
+<a href="#posbases">Positions</a>
+<a href="#packages">Packages</a>
+<a href="#types">Types</a>
+<a href="#objects">Objects</a>
+<a href="#public-root">Public root</a>
+<a href="#private-root">Private root</a>
+<a href="#generic-bodies">Generic bodies</a>
+</nav>
+
+<h2 id="build">Build Metadata</h2>
+<table>
+<tr><th>Import path</th><td>{{with .Build.ImportPath}}<code>{{.}}</code> (from {{$.Build.ImportPathSource}}){{else}}(unknown){{end}}</td></tr>
+<tr><th>File</th><td><code>{{.Build.File}}</code></td></tr>
+<tr><th>Container</th><td>{{.Build.Container}}</td></tr>
+{{- with .Build.Header}}
+<tr><th>Target</th><td>{{.GOOS}}/{{.GOARCH}}{{with .ArchVariant}} ({{.}}){{end}}</td></tr>
+<tr><th>Toolchain</th><td>{{.Toolchain}}</td></tr>
+<tr><th>Experiments</th><td>{{range $i, $e := .Experiments}}{{if $i}}, {{end}}{{$e}}{{else}}(none){{end}}</td></tr>
+<tr><th>Build ID</th><td>{{with .BuildID}}<code>{{.}}</code>{{else}}(none){{end}}</td></tr>
+{{- if .Main}}
+<tr><th>Package main</th><td>true</td></tr>
+{{- end}}
+{{- range .Other}}
+<tr><th>Other</th><td><code>{{.}}</code></td></tr>
+{{- end}}
+{{- else}}
+<tr><th>Object header</th><td>(raw Unified IR has none)</td></tr>
+{{- end}}
+</table>
+
+<h2 id="format">Format Metadata</h2>
+<table>
+<tr><th>Version</th><td>V{{.Format.Version}}</td></tr>
+<tr><th>Sync markers</th><td>{{.Format.SyncMarkers}}</td></tr>
+<tr><th>Total elements</th><td>{{.Format.TotalElements}}</td></tr>
+<tr><th>Fingerprint</th><td><code>{{.Format.Fingerprint}}</code> {{if .Format.FingerprintVerified}}(verified){{else}}<span class="error">(MISMATCH: data hashes to <code>{{.Format.ComputedFingerprint}}</code>)</span>{{end}}</td></tr>
+</table>
+
+<h2 id="sections">Section Statistics</h2>
+<table>
+<tr><th>Section</th><th>Elements</th></tr>
+{{- range .Sections}}
+<tr><td>{{.Name}}</td><td class="num">{{.Elements}}</td></tr>
+{{- end}}
+</table>
+{{with .ObjectKinds}}
+<p></p>
+<table>
+<tr><th>Object kind</th><th>Count</th></tr>
+{{- range .}}
+<tr><td>{{.Tag}}</td><td class="num">{{.Count}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+<h2 id="strings">SectionString — Deduplicated Strings</h2>
+{{with .Strings}}
+<table>
+<tr><th>Element</th><th>Value</th></tr>
+{{- range .}}
+<tr id="SectionString-{{.Index}}"><td class="mono">{{.Index}}</td><td class="mono">{{quote .Value}}</td></tr>
+{{- end}}
+</table>
+{{else}}<p class="note">(empty)</p>{{end}}
+{{template "more" (more (len .Strings) (index .Sections 0).Elements)}}
+
+<h2 id="posbases">SectionPosBase — Source File Locations</h2>
+{{range .PosBases}}
+<div class="elem" id="SectionPosBase-{{.Index}}">
+<code>[{{.Index}}] {{.Filename}}</code>
+{{if .FileBase}}(file base){{else}}(line base at {{.Pos}}, starting at line {{.Line}}{{if .Col}}, column {{.Col}}{{end}}){{end}}
+{{template "refs" .Refs}}
+</div>
+{{else}}<p class="note">(none)</p>{{end}}
+{{template "more" (more (len .PosBases) (index .Sections 2).Elements)}}
+
+<h2 id="packages">SectionPkg — Package References</h2>
+{{range .Packages}}
+<div class="elem" id="SectionPkg-{{.Index}}">
+{{if .Error}}<span class="error">[{{.Index}}] error reading package: {{.Error}}</span>
+{{else}}<code>[{{.Index}}] {{with .Path}}{{.}}{{else}}&lt;self&gt;{{end}}</code> (name: {{.Name}}{{if .Self}}, self{{end}})
+{{with .Archive}}<div class="note">archive: <code>{{.}}</code></div>{{end}}
+{{template "refs" .Refs}}
+{{end}}
+</div>
+{{else}}<p class="note">(none)</p>{{end}}
+{{template "more" (more (len .Packages) (index .Sections 3).Elements)}}
+
+<h2 id="types">SectionType — Type Definitions</h2>
+<p class="note">$N is type parameter N, derived#N is derived type N of the owning dictionary</p>
+{{range .Types}}
+<div class="elem" id="SectionType-{{.Index}}">
+{{if .Error}}<span class="error">[{{.Index}}] error reading type: {{.Error}}</span>
+{{else}}<code>[{{.Index}}] {{.Code}} {{.Type}}</code>
+{{template "refs" .Refs}}
+{{end}}
+</div>
+{{end}}
+{{template "more" (more (len .Types) (index .Sections 5).Elements)}}
+
+<h2 id="objects">SectionObj — Object Declarations</h2>
+{{range .Objects}}
+<div class="elem" id="SectionObj-{{.Index}}">
+{{if .Error}}<span class="error">[{{.Index}}] error reading object: {{.Error}}</span>
+{{else}}<code>[{{.Index}}] {{.Tag}} {{.Decl}}</code>
+{{with .Pos}}<div class="note">pos: {{.}}</div>{{end}}
+{{range .Dict}}<div class="note">dict: {{linkify .}}</div>{{end}}
+{{with .Ext}}<div class="note">ext: {{linkify .}}</div>{{end}}
+{{range .Methods}}
+<div class="elem"><code>{{.Decl}}</code>
+<div class="note">pos: {{.Pos}}</div>
+{{with .Ext}}<div class="note">ext: {{linkify .}}</div>{{end}}
+</div>
+{{end}}
+{{template "refs" .Refs}}
+{{end}}
+</div>
+{{else}}<p class="note">(none)</p>{{end}}
+{{template "more" (more (len .Objects) (index .Sections 6).Elements)}}
+
+<h2 id="public-root">SectionMeta — Public Root (Package Exports)</h2>
+{{with .PublicRoot}}
+<p>Package: <code>{{with .Path}}{{.}}{{else}}&lt;self&gt;{{end}}</code> (name: {{.Name}})
+{{- with .HasInit}}<br>Has init: {{.}}{{end}}
+<br>Exported objects: {{.ExportCount}} (in importer order)</p>
+<table>
+{{- range .Exports}}
+<tr>{{$e := .}}<td class="mono">{{.Index}}</td>{{if .Error}}<td colspan="2" class="error">error reading {{template "link" (printf "SectionObj:%d" .ObjectIndex)}}: {{.Error}}</td>{{else}}<td>{{.Tag}}</td><td>{{with href (printf "SectionObj:%d" .ObjectIndex)}}<a href="{{.}}"><code>{{$e.Decl}}</code></a>{{else}}<code>{{$e.Decl}}</code>{{end}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{template "more" (more (len .Exports) .ExportCount)}}
+{{end}}
+
+<h2 id="private-root">SectionMeta — Private Root (Function Bodies)</h2>
+{{with .PrivateRoot}}
+<p>Has .inittask: {{.HasInittask}}<br>Function bodies: {{.BodyCount}}</p>
+{{template "bodies" .Bodies}}
+{{template "more" (more (len .Bodies) .BodyCount)}}
+{{end}}
+
+<h2 id="generic-bodies">SectionBody — Generic Function Bodies (compiled by importers)</h2>
+{{with .GenericBodies}}
+<p>Generic function bodies: {{.BodyCount}}</p>
+{{template "bodies" .Bodies}}
+{{template "more" (more (len .Bodies) .BodyCount)}}
+{{end}}
+</body>
+</html>
+{{define "bodies"}}{{range .}}
+<div class="elem" id="SectionBody-{{.BodyIndex}}">
+<code>[{{.Index}}] {{.Package}}.{{.Symbol}}</code> (body index: {{.BodyIndex}})
+{{if .Error}}<div class="error">{{.Error}}</div>{{else}}<pre>{{.Source}}</pre>{{end}}
+{{template "refs" .Refs}}
+</div>
+{{end}}{{end}}
+{{define "refs"}}{{with .}}<div class="refs">refs:{{range .}} {{template "link" .}}{{end}}</div>{{end}}{{end}}
+{{define "link"}}{{$ref := .}}{{with href .}}<a href="{{.}}">{{$ref}}</a>{{else}}{{$ref}}{{end}}{{end}}
+{{define "more"}}{{if .}}<p class="note">… and {{.}} more</p>{{end}}{{end}}
+`))
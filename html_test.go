@@ -0,0 +1,56 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHTMLLinks(t *testing.T) {
+	f := rawFile(examplePackage())
+	idRE := regexp.MustCompile(`\bid="([^"]*)"`)
+	hrefRE := regexp.MustCompile(`\bhref="([^"]*)"`)
+
+	for _, limit := range []int{0, 3} {
+		rep, err := newReport(f, nil, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		if err := rep.writeHTML(&b); err != nil {
+			t.Fatal(err)
+		}
+		page := b.String()
+
+		ids := make(map[string]bool)
+		for _, m := range idRE.FindAllStringSubmatch(page, -1) {
+			ids[m[1]] = true
+		}
+		hrefs := hrefRE.FindAllStringSubmatch(page, -1)
+		if len(hrefs) == 0 {
+			t.Errorf("limit %d: no links", limit)
+		}
+		for _, m := range hrefs {
+			if target, ok := strings.CutPrefix(m[1], "#"); !ok || !ids[target] {
+				t.Errorf("limit %d: link %q has no target", limit, m[1])
+			}
+		}
+	}
+}
+
+func TestElemAnchor(t *testing.T) {
+	for ref, want := range map[string]string{
+		"SectionObj:12":     "SectionObj-12",
+		"SectionObjDict:12": "SectionObj-12",
+		"SectionName:3":     "SectionObj-3",
+		"SectionBody[3]":    "SectionBody-3",
+		"SectionMeta:0":     "public-root",
+		"SectionMeta:1":     "private-root",
+		"SectionObj":        "",
+		"SectionObj:x":      "",
+	} {
+		if got := elemAnchor(ref); got != want {
+			t.Errorf("elemAnchor(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// An importCfg holds the package mappings of an importcfg file, as
+// passed to the compiler and linker by the go command.
+type importCfg struct {
+	packageFile map[string]string // import path -> export data file
+	importMap   map[string]string // source import path -> actual import path
+}
+
+// readImportCfg reads the importcfg file filename. Only packagefile and
+// importmap directives are used; others are ignored.
+func readImportCfg(filename string) (*importCfg, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cfg := &importCfg{
+		packageFile: make(map[string]string),
+		importMap:   make(map[string]string),
+	}
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		verb, args, _ := strings.Cut(line, " ")
+		switch verb {
+		case "packagefile":
+			path, file, ok := strings.Cut(args, "=")
+			if !ok || path == "" || file == "" {
+				return nil, fmt.Errorf("%s:%d: invalid packagefile: syntax is \"packagefile path=filename\"", filename, lineNum)
+			}
+			cfg.packageFile[path] = file
+		case "importmap":
+			source, actual, ok := strings.Cut(args, "=")
+			if !ok || source == "" || actual == "" {
+				return nil, fmt.Errorf("%s:%d: invalid importmap: syntax is \"importmap old=new\"", filename, lineNum)
+			}
+			cfg.importMap[source] = actual
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	return cfg, nil
+}
+
+// resolve returns the path of the package imported as path, after any
+// importmap.
+func (cfg *importCfg) resolve(path string) string {
+	if cfg == nil {
+		return path
+	}
+	if actual, ok := cfg.importMap[path]; ok {
+		return actual
+	}
+	return path
+}
+
+// lookup returns the export data file of the package imported as path.
+func (cfg *importCfg) lookup(path string) (string, bool) {
+	if cfg == nil {
+		return "", false
+	}
+	file, ok := cfg.packageFile[cfg.resolve(path)]
+	return file, ok
+}
+
+// pathOf returns the import path that the export data file filename is
+// mapped from, or "" if there is none.
+func (cfg *importCfg) pathOf(filename string) string {
+	if cfg == nil {
+		return ""
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return ""
+	}
+	for _, path := range slices.Sorted(maps.Keys(cfg.packageFile)) {
+		if f, err := filepath.Abs(cfg.packageFile[path]); err == nil && f == abs {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadImportCfg(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "importcfg")
+	const cfgData = `# import config
+packagefile fmt=/cache/fmt.a
+packagefile example.com/example=example.a
+
+importmap old/path=example.com/example
+modinfo "ignored"
+`
+	if err := os.WriteFile(filename, []byte(cfgData), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := readImportCfg(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{
+		"fmt":                 "/cache/fmt.a",
+		"example.com/example": "example.a",
+		"old/path":            "example.a",
+		"os":                  "",
+	} {
+		if got, ok := cfg.lookup(path); got != want || ok != (want != "") {
+			t.Errorf("lookup(%q) = %q, %v, want %q", path, got, ok, want)
+		}
+	}
+	if got, ok := (*importCfg)(nil).lookup("fmt"); ok {
+		t.Errorf("nil importcfg has %q for fmt", got)
+	}
+	if got := cfg.pathOf("/cache/fmt.a"); got != "fmt" {
+		t.Errorf("pathOf(/cache/fmt.a) = %q, want fmt", got)
+	}
+	if got := cfg.pathOf("/cache/os.a"); got != "" {
+		t.Errorf("pathOf(/cache/os.a) = %q, want none", got)
+	}
+
+	for _, test := range []struct{ line, err string }{
+		{"packagefile fmt", "importcfg:1: invalid packagefile"},
+		{"packagefile =x.a", "importcfg:1: invalid packagefile"},
+		{"importmap a=", "importcfg:1: invalid importmap"},
+	} {
+		if err := os.WriteFile(filename, []byte(test.line+"\n"), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readImportCfg(filename); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q gives error %v, want %q", test.line, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Container is a kind of file holding export data.
+type Container int
+
+const (
+	UnknownContainer Container = iota
+	ArchiveContainer           // ar archive, with the export data in its __.PKGDEF member
+	ObjectContainer            // bare object file, as written by "go tool compile" without -pack
+	RawContainer               // unified IR on its own, starting with 'u'
+)
+
+func (c Container) String() string {
+	switch c {
+	case ArchiveContainer:
+		return "ar archive"
+	case ObjectContainer:
+		return "object file"
+	case RawContainer:
+		return "raw unified IR"
+	}
+	return "unknown"
+}
+
+// DetectContainer reports which kind of container data is.
+func DetectContainer(data []byte) Container {
+	switch {
+	case bytes.HasPrefix(data, []byte(arMagic)), bytes.HasPrefix(data, []byte(thinArMagic)):
+		return ArchiveContainer
+	case bytes.HasPrefix(data, []byte("go object ")):
+		return ObjectContainer
+	case len(data) > 0 && data[0] == 'u':
+		return RawContainer
+	}
+	return UnknownContainer
+}
+
+// ExtractExportData extracts the Unified IR data, including the 'u'
+// prefix, from an archive, a bare object file or raw Unified IR,
+// detecting which with DetectContainer.
+func ExtractExportData(data []byte) ([]byte, Container, error) {
+	c := DetectContainer(data)
+	switch c {
+	case ArchiveContainer:
+		pkgdef, err := ExtractPKGDEF(data)
+		if err != nil {
+			return nil, c, err
+		}
+		uir, err := ExtractUnifiedIR(pkgdef)
+		return uir, c, err
+
+	case ObjectContainer:
+		// The export data is followed by the "\n!\n" that starts the
+		// object data proper; stop there, so the end marker isn't
+		// searched for in the object data.
+		if end := bytes.Index(data, []byte("\n$$\n\n!\n")); end >= 0 {
+			data = data[:end+len("\n$$\n")]
+		}
+		uir, err := ExtractUnifiedIR(data)
+		return uir, c, err
+
+	case RawContainer:
+		return data, c, nil
+	}
+	return nil, c, fmt.Errorf("not an archive, object file or unified IR")
+}
+
+// An ArchiveMember is a file stored in an ar archive.
+type ArchiveMember struct {
+	Name   string
+	Offset int    // offset of the member's header within the archive
+	Size   int    // size of the member's contents
+	Data   []byte // the member's contents, without padding; nil if External
+
+	// External reports whether the member is stored outside a thin
+	// archive, in File. See LoadThinMembers.
+	External bool
+
+	// File is the file holding an External member, relative to the
+	// archive's directory.
+	File string
+
+	// NestedOffset is set for External members that are themselves
+	// members of the archive in File: GNU ar adds the members of an
+	// archive to a thin archive, rather than the archive itself. It is
+	// the offset of the member's header within File, whose Name is
+	// only known once LoadThinMembers reads it; until then, Name is
+	// File.
+	NestedOffset int
+}
+
+const (
+	arMagic     = "!<arch>\n"
+	thinArMagic = "!<thin>\n"
+	arHdrSize   = 60
+)
+
+// ArchiveMembers returns the members of a .a archive, in order. It
+// understands the common ar variants: the GNU "//" table of long names
+// and "/" suffixes, BSD "#1/NN" names stored ahead of the contents, and
+// GNU thin archives, whose members are External, including the members
+// of nested archives. The symbol table and
+// long name table are returned as members too.
+//
+// If the archive is malformed, it returns the members before the
+// problem along with an error.
+func ArchiveMembers(data []byte) ([]ArchiveMember, error) {
+	thin := bytes.HasPrefix(data, []byte(thinArMagic))
+	if !thin && !bytes.HasPrefix(data, []byte(arMagic)) {
+		return nil, fmt.Errorf("not a valid archive file")
+	}
+
+	var members []ArchiveMember
+	var longNames []byte // contents of the GNU "//" member
+	offset := len(arMagic)
+
+	for offset < len(data) {
+		if offset+arHdrSize > len(data) {
+			return members, fmt.Errorf("truncated archive member header at offset %d", offset)
+		}
+		header := data[offset : offset+arHdrSize]
+		if string(header[58:60]) != "`\n" {
+			return members, fmt.Errorf("malformed archive member header at offset %d: bad terminator %q", offset, header[58:60])
+		}
+
+		sizeStr := strings.TrimRight(string(header[48:58]), " ")
+		size, err := strconv.ParseUint(sizeStr, 10, 31)
+		if err != nil {
+			return members, fmt.Errorf("malformed archive member header at offset %d: bad size %q", offset, sizeStr)
+		}
+		m := ArchiveMember{Offset: offset, Size: int(size)}
+		start := offset + arHdrSize
+
+		name := strings.TrimRight(string(header[0:16]), " ")
+		special := name == "/" || name == "/SYM64/" || name == "//"
+		switch {
+		case special:
+			// GNU symbol table and long name table. Their contents
+			// are stored even in thin archives.
+			m.Name = name
+
+		case strings.HasPrefix(name, "#1/"):
+			// BSD long name, stored ahead of the contents and
+			// counted in the size.
+			n, err := strconv.ParseUint(name[len("#1/"):], 10, 31)
+			if err != nil || int(n) > m.Size {
+				return members, fmt.Errorf("malformed archive member header at offset %d: bad BSD name %q", offset, name)
+			}
+			if start+int(n) > len(data) {
+				return members, fmt.Errorf("truncated archive member name at offset %d", start)
+			}
+			m.Name = strings.TrimRight(string(data[start:start+int(n)]), "\x00")
+			start += int(n)
+			m.Size -= int(n)
+
+		case strings.HasPrefix(name, "/"):
+			// GNU long name: an offset into the "//" member, where
+			// names end with "/\n". In thin archives, the name of
+			// a nested archive is followed by ":offset", locating
+			// the member within it.
+			num, nested, isNested := strings.Cut(name[1:], ":")
+			n, err := strconv.ParseUint(num, 10, 31)
+			if err != nil {
+				return members, fmt.Errorf("malformed archive member header at offset %d: bad name %q", offset, name)
+			}
+			if isNested {
+				off, err := strconv.ParseUint(nested, 10, 31)
+				if err != nil || !thin || off == 0 {
+					return members, fmt.Errorf("malformed archive member header at offset %d: bad nested member name %q", offset, name)
+				}
+				m.NestedOffset = int(off)
+			}
+			if longNames == nil || int(n) >= len(longNames) {
+				return members, fmt.Errorf("malformed archive member header at offset %d: long name %q not in the name table", offset, name)
+			}
+			long := longNames[n:]
+			if end := bytes.Index(long, []byte("/\n")); end >= 0 {
+				long = long[:end]
+			} else if end := bytes.IndexByte(long, '\n'); end >= 0 {
+				long = long[:end]
+			}
+			m.Name = string(long)
+
+		default:
+			// GNU ar ends short names with '/', so they may contain
+			// spaces; BSD ar pads them with spaces.
+			m.Name = strings.TrimSuffix(name, "/")
+		}
+
+		m.External = thin && !special
+		if m.External {
+			m.File = m.Name
+			members = append(members, m)
+			offset = start
+			continue
+		}
+
+		if start+m.Size > len(data) {
+			return members, fmt.Errorf("truncated archive: member %q at offset %d needs %d bytes, have %d", m.Name, offset, m.Size, len(data)-start)
+		}
+		m.Data = data[start : start+m.Size]
+		if m.Name == "//" {
+			longNames = m.Data
+		}
+		members = append(members, m)
+
+		// Move to next entry (entries are 2-byte aligned)
+		offset = start + m.Size
+		if offset%2 == 1 {
+			offset++ // Skip padding byte
+		}
+	}
+
+	return members, nil
+}
+
+// LoadThinMembers reads the contents of the External members of a thin
+// archive stored in directory dir, and the names of nested members.
+func LoadThinMembers(members []ArchiveMember, dir string) error {
+	nested := make(map[string][]ArchiveMember) // members of nested archives, by file
+	for i := range members {
+		m := &members[i]
+		if !m.External {
+			continue
+		}
+		name := m.File
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+
+		if m.NestedOffset != 0 {
+			if _, ok := nested[name]; !ok {
+				data, err := os.ReadFile(name)
+				if err != nil {
+					return fmt.Errorf("reading thin archive member: %w", err)
+				}
+				// The members before any problem may still
+				// include this one.
+				nested[name], _ = ArchiveMembers(data)
+			}
+			j := slices.IndexFunc(nested[name], func(nm ArchiveMember) bool { return nm.Offset == m.NestedOffset })
+			if j < 0 {
+				return fmt.Errorf("thin archive member %s has no member at offset %d", name, m.NestedOffset)
+			}
+			nm := nested[name][j]
+			if nm.Data == nil || len(nm.Data) != m.Size {
+				return fmt.Errorf("thin archive member %s at offset %d in %s has %d bytes, but the archive records %d", nm.Name, nm.Offset, name, len(nm.Data), m.Size)
+			}
+			m.Name, m.Data = nm.Name, nm.Data
+			continue
+		}
+
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("reading thin archive member: %w", err)
+		}
+		if len(data) != m.Size {
+			return fmt.Errorf("thin archive member %s has %d bytes, but the archive records %d", name, len(data), m.Size)
+		}
+		m.Data = data
+	}
+	return nil
+}
+
+// ExpandThinArchive returns a regular archive with the members of the
+// thin archive data, which is stored in directory dir. Other data is
+// returned unchanged.
+func ExpandThinArchive(data []byte, dir string) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(thinArMagic)) {
+		return data, nil
+	}
+	members, err := ArchiveMembers(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := LoadThinMembers(members, dir); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(arMagic)
+	for _, m := range members {
+		if m.Name == "/" || m.Name == "/SYM64/" || m.Name == "//" {
+			// Symbol offsets and long names would be stale.
+			continue
+		}
+		// Regular archives hold base names, as __.PKGDEF is looked
+		// up by name.
+		base := filepath.Base(m.Name)
+		name, size := base, len(m.Data)
+		if len(name) > 16 || strings.ContainsAny(name, " /") {
+			// Use a BSD long name, which needs no table.
+			name, size = fmt.Sprintf("#1/%d", len(base)), size+len(base)
+		}
+		fmt.Fprintf(&buf, "%-16s%-12d%-6d%-6d%-8o%-10d`\n", name, 0, 0, 0, 0o644, size)
+		if strings.HasPrefix(name, "#1/") {
+			buf.WriteString(base)
+		}
+		buf.Write(m.Data)
+		if buf.Len()%2 == 1 {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// ExtractPKGDEF extracts the __.PKGDEF section from a .a archive
+func ExtractPKGDEF(data []byte) ([]byte, error) {
+	members, err := ArchiveMembers(data)
+	for _, m := range members {
+		if m.Name == "__.PKGDEF" {
+			if m.External {
+				return nil, fmt.Errorf("__.PKGDEF is stored outside the thin archive, in %s", m.File)
+			}
+			return m.Data, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("__.PKGDEF not found in archive")
+}
+
+// MemberKind describes the contents of an archive member or other
+// file, as found by looking at its first bytes.
+func MemberKind(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("go object ")):
+		// __.PKGDEF holds the export data after the object header,
+		// while _go_.o holds the linker object after "\n!\n".
+		exp := bytes.Index(data, []byte("\n$$B\n"))
+		obj := bytes.Index(data, []byte("\n!\n"))
+		if exp >= 0 && (obj < 0 || exp < obj) {
+			return "Go export data"
+		}
+		return "Go object"
+	case bytes.HasPrefix(data, []byte(arMagic)), bytes.HasPrefix(data, []byte(thinArMagic)):
+		return "ar archive"
+	case bytes.HasPrefix(data, []byte("\x7fELF")):
+		return "ELF object"
+	case bytes.HasPrefix(data, []byte{0xcf, 0xfa, 0xed, 0xfe}), bytes.HasPrefix(data, []byte{0xce, 0xfa, 0xed, 0xfe}):
+		return "Mach-O object"
+	case bytes.HasPrefix(data, []byte{0x64, 0x86}), bytes.HasPrefix(data, []byte{0x4c, 0x01}), bytes.HasPrefix(data, []byte{0x64, 0xaa}):
+		return "COFF object"
+	case bytes.HasPrefix(data, []byte{0x01, 0xdf}), bytes.HasPrefix(data, []byte{0x01, 0xf7}):
+		return "XCOFF object"
+	case len(data) == 0:
+		return "empty"
+	case utf8.Valid(data) && bytes.IndexByte(data, 0) < 0:
+		return "text"
+	}
+	return "unknown"
+}
+
+// ExtractUnifiedIR extracts the Unified IR data from __.PKGDEF content
+func ExtractUnifiedIR(pkgdefData []byte) ([]byte, error) {
+	// The format is:
+	// \n$$B\n
+	// u<unified-ir-data>
+	// \n$$\n
+
+	start := bytes.Index(pkgdefData, []byte("\n$$B\n"))
+	if start == -1 {
+		return nil, fmt.Errorf("could not find export data start marker")
+	}
+	start += 5 // Skip "\n$$B\n"
+
+	// The export data runs to the end of __.PKGDEF, so prefer the
+	// final marker over searching the binary data for one.
+	end := len(pkgdefData[start:]) - len("\n$$\n")
+	if !bytes.HasSuffix(pkgdefData, []byte("\n$$\n")) {
+		end = bytes.Index(pkgdefData[start:], []byte("\n$$\n"))
+	}
+	if end < 0 {
+		return nil, fmt.Errorf("could not find export data end marker")
+	}
+
+	exportData := pkgdefData[start : start+end]
+
+	// Check for 'u' prefix indicating unified IR
+	if len(exportData) == 0 || exportData[0] != 'u' {
+		return nil, fmt.Errorf("not unified IR format (expected 'u' prefix)")
+	}
+
+	// Return the complete export data including the 'u' prefix
+	return exportData, nil
+}
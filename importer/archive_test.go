@@ -0,0 +1,270 @@
+package importer_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/importer"
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+)
+
+// arHeader returns an ar member header for a member of the given name
+// and size.
+func arHeader(name string, size int) string {
+	return fmt.Sprintf("%-16s%-12d%-6d%-6d%-8o%-10d`\n", name, 0, 0, 0, 0o644, size)
+}
+
+// A wantMember is the name and contents of a member of a test archive;
+// data is ignored for External members.
+type wantMember struct {
+	name     string
+	data     string
+	external bool
+}
+
+func TestExtractExportData(t *testing.T) {
+	// The end marker may also appear in the export data and in the
+	// object data that follows it.
+	data := []byte("u\x02\x00\x00\x00data\n$$\nmore data")
+	obj := "object data\n$$\n"
+
+	tests := []struct {
+		name      string
+		file      []byte
+		container importer.Container
+	}{
+		{"archive", uirtest.Archive(uirtest.Header, data, obj), importer.ArchiveContainer},
+		{"object", uirtest.ObjectFile(uirtest.Header, data, obj), importer.ObjectContainer},
+		{"raw", data, importer.RawContainer},
+	}
+	for _, tt := range tests {
+		got, c, err := importer.ExtractExportData(tt.file)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if c != tt.container {
+			t.Errorf("%s: found %v, want %v", tt.name, c, tt.container)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: extracted %q, want %q", tt.name, got, data)
+		}
+	}
+
+	if _, c, err := importer.ExtractExportData([]byte("\x7fELF")); err == nil || c != importer.UnknownContainer {
+		t.Errorf("ExtractExportData(ELF) = %v, %v, want an unknown container error", c, err)
+	}
+	noPKGDEF := []byte("!<arch>\n" + uirtest.Member("_go_.o", "obj"))
+	if _, _, err := importer.ExtractExportData(noPKGDEF); err == nil {
+		t.Errorf("ExtractExportData of an archive without __.PKGDEF succeeded")
+	}
+}
+
+func TestDetectContainer(t *testing.T) {
+	tests := []struct {
+		data string
+		want importer.Container
+	}{
+		{"!<arch>\n", importer.ArchiveContainer},
+		{"!<thin>\n", importer.ArchiveContainer},
+		{"go object linux amd64 go1.23\n", importer.ObjectContainer},
+		{"u\x00\x00\x00\x00", importer.RawContainer},
+		{"", importer.UnknownContainer},
+		{"\x7fELF", importer.UnknownContainer},
+	}
+	for _, tt := range tests {
+		if got := importer.DetectContainer([]byte(tt.data)); got != tt.want {
+			t.Errorf("DetectContainer(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestArchiveMembers(t *testing.T) {
+	const longName = "a_member_with_a_long_name.o"
+	tests := []struct {
+		name    string
+		archive string
+		want    []wantMember
+		err     string // substring of the error, if any
+	}{
+		{
+			name:    "empty",
+			archive: "!<arch>\n",
+		},
+		{
+			name:    "GNU",
+			archive: "!<arch>\n" + uirtest.Member("//", longName+"/\n") + uirtest.Member("__.PKGDEF/", "export") + uirtest.Member("/0", "odd"),
+			want:    []wantMember{{name: "//", data: longName + "/\n"}, {name: "__.PKGDEF", data: "export"}, {name: longName, data: "odd"}},
+		},
+		{
+			name:    "BSD",
+			archive: "!<arch>\n" + arHeader("#1/28", 28+5) + longName + "\x00data!" + "\n" + uirtest.Member("__.PKGDEF", "export"),
+			want:    []wantMember{{name: longName, data: "data!"}, {name: "__.PKGDEF", data: "export"}},
+		},
+		{
+			name:    "thin",
+			archive: "!<thin>\n" + uirtest.Member("//", "dir/"+longName+"/\n") + arHeader("/0", 10) + arHeader("short.o/", 3),
+			want:    []wantMember{{name: "//", data: "dir/" + longName + "/\n"}, {name: "dir/" + longName, external: true}, {name: "short.o", external: true}},
+		},
+		{
+			name:    "thin nested",
+			archive: "!<thin>\n" + uirtest.Member("//", "pkg.o/\n") + arHeader("/0:8", 6) + arHeader("/0:76", 4),
+			want:    []wantMember{{name: "//", data: "pkg.o/\n"}, {name: "pkg.o", external: true}, {name: "pkg.o", external: true}},
+		},
+		{
+			name:    "not an archive",
+			archive: "go object linux amd64",
+			err:     "not a valid archive",
+		},
+		{
+			name:    "truncated header",
+			archive: "!<arch>\n" + uirtest.Member("__.PKGDEF", "export") + arHeader("_go_.o", 4)[:30],
+			want:    []wantMember{{name: "__.PKGDEF", data: "export"}},
+			err:     "truncated archive member header at offset 74",
+		},
+		{
+			name:    "truncated member",
+			archive: "!<arch>\n" + uirtest.Member("__.PKGDEF", "export") + arHeader("_go_.o", 100) + "short",
+			want:    []wantMember{{name: "__.PKGDEF", data: "export"}},
+			err:     `truncated archive: member "_go_.o" at offset 74 needs 100 bytes, have 5`,
+		},
+		{
+			name:    "bad terminator",
+			archive: "!<arch>\n" + strings.Replace(uirtest.Member("__.PKGDEF", "export"), "`\n", "xx", 1),
+			err:     `bad terminator "xx"`,
+		},
+		{
+			name:    "bad size",
+			archive: "!<arch>\n" + "__.PKGDEF       0           0     0     644     12x4      `\n",
+			err:     `bad size "12x4"`,
+		},
+		{
+			name:    "bad BSD name",
+			archive: "!<arch>\n" + uirtest.Member("#1/99", "short"),
+			err:     `bad BSD name "#1/99"`,
+		},
+		{
+			name:    "long name without table",
+			archive: "!<arch>\n" + uirtest.Member("/0", "data"),
+			err:     `long name "/0" not in the name table`,
+		},
+		{
+			name:    "nested member outside thin archive",
+			archive: "!<arch>\n" + uirtest.Member("//", "pkg.o/\n") + uirtest.Member("/0:8", "data"),
+			want:    []wantMember{{name: "//", data: "pkg.o/\n"}},
+			err:     `bad nested member name "/0:8"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members, err := importer.ArchiveMembers([]byte(tt.archive))
+			if tt.err == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+			checkMembers(t, members, tt.want)
+		})
+	}
+}
+
+// checkMembers reports the differences between members and want.
+func checkMembers(t *testing.T, members []importer.ArchiveMember, want []wantMember) {
+	t.Helper()
+	if len(members) != len(want) {
+		t.Errorf("got %d members, want %d", len(members), len(want))
+	}
+	for i, m := range members[:min(len(members), len(want))] {
+		w := want[i]
+		if m.Name != w.name || m.External != w.external {
+			t.Errorf("member %d is %q (external %v), want %q (external %v)", i, m.Name, m.External, w.name, w.external)
+		}
+		if !w.external && string(m.Data) != w.data {
+			t.Errorf("member %s has data %q, want %q", m.Name, m.Data, w.data)
+		}
+	}
+}
+
+func TestExpandThinArchive(t *testing.T) {
+	dir := t.TempDir()
+	// pkg.o is an archive itself, as Go object files are; GNU ar adds
+	// its members to thin archives rather than the file.
+	nested := "!<arch>\n" + uirtest.Member("__.PKGDEF", "export") + uirtest.Member("_go_.o", "obj")
+	files := map[string]string{
+		"sub/a_member_with_a_long_name.o": "long data",
+		"short.o":                         "short",
+		"pkg.o":                           nested,
+	}
+	for name, data := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := "sub/a_member_with_a_long_name.o/\npkg.o/\n"
+	thin := "!<thin>\n" + uirtest.Member("//", names) +
+		arHeader("/0", len("long data")) +
+		arHeader("short.o/", len("short")) +
+		arHeader(fmt.Sprintf("/%d:8", strings.Index(names, "pkg.o")), len("export")) +
+		arHeader(fmt.Sprintf("/%d:%d", strings.Index(names, "pkg.o"), strings.Index(nested, "_go_.o")), len("obj"))
+
+	data, err := importer.ExpandThinArchive([]byte(thin), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := importer.ArchiveMembers(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMembers(t, members, []wantMember{
+		{name: "a_member_with_a_long_name.o", data: "long data"},
+		{name: "short.o", data: "short"},
+		{name: "__.PKGDEF", data: "export"},
+		{name: "_go_.o", data: "obj"},
+	})
+
+	// Regular archives are returned unchanged.
+	regular := "!<arch>\n" + uirtest.Member("short.o", "short")
+	if data, err := importer.ExpandThinArchive([]byte(regular), dir); err != nil || string(data) != regular {
+		t.Errorf("ExpandThinArchive(regular) = %q, %v, want it unchanged", data, err)
+	}
+
+	// Members must match the sizes the archive records.
+	bad := "!<thin>\n" + arHeader("short.o/", 4)
+	if _, err := importer.ExpandThinArchive([]byte(bad), dir); err == nil || !strings.Contains(err.Error(), "has 5 bytes, but the archive records 4") {
+		t.Errorf("ExpandThinArchive with a wrong size: %v", err)
+	}
+	missing := "!<thin>\n" + arHeader("missing.o/", 4)
+	if _, err := importer.ExpandThinArchive([]byte(missing), dir); err == nil {
+		t.Errorf("ExpandThinArchive with a missing member succeeded")
+	}
+}
+
+func TestMemberKind(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{uirtest.Header + "\n$$B\nu", "Go export data"},
+		{uirtest.Header + "\n!\ngo13ld", "Go object"},
+		{"!<arch>\n", "ar archive"},
+		{"\x7fELF\x02", "ELF object"},
+		{"\xcf\xfa\xed\xfe", "Mach-O object"},
+		{"", "empty"},
+		{"hello\n", "text"},
+		{"\x00\x01", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := importer.MemberKind([]byte(tt.data)); got != tt.want {
+			t.Errorf("MemberKind(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
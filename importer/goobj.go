@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// GoObjMagic is the magic string that starts the linker object, as in
+// cmd/internal/goobj.
+const GoObjMagic = "\x00go120ld"
+
+// Blocks of the linker object, as in cmd/internal/goobj. The header
+// records the offset at which each starts.
+const (
+	blkAutolib = iota
+	blkPkgIdx
+	blkFile
+	blkSymdef
+	blkHashed64def
+	blkHasheddef
+	blkNonpkgdef
+	blkNonpkgref
+	blkRefFlags
+	blkHash64
+	blkHash
+	blkRelocIdx
+	blkAuxIdx
+	blkDataIdx
+	blkReloc
+	blkAux
+	blkData
+	blkRefName
+	blkEnd
+	nBlk
+)
+
+// Sizes of fixed-size linker object entries, as in cmd/internal/goobj.
+const (
+	goobjSymSize   = 8 + 2 + 1 + 1 + 1 + 4 + 4
+	goobjRelocSize = 4 + 1 + 2 + 8 + 8
+)
+
+// Linker object flags, as in cmd/internal/goobj.
+const (
+	GoObjFlagShared       = 1 << 0 // built with -shared
+	GoObjFlagFromAssembly = 1 << 2 // assembled rather than compiled
+	GoObjFlagUnlinkable   = 1 << 3 // the linker will refuse it
+	GoObjFlagStd          = 1 << 4 // standard library package
+)
+
+// A GoObjHeader is the decoded header of a linker object, the Go object
+// file format stored in _go_.o and in assembled archive members.
+type GoObjHeader struct {
+	Magic       string
+	Fingerprint [8]byte // matches the export data's fingerprint
+	Flags       uint32
+
+	// Symbol counts: package symbols, content-addressable symbols
+	// with short and full hashes, other symbols defined and those
+	// referenced by name.
+	NSyms, NHashed64Defs, NHashedDefs, NNonPkgDefs, NNonPkgRefs int
+
+	NRelocs int
+}
+
+// ParseGoObjHeader decodes the linker object header of data, which is
+// an object file starting with a "go object" text header, such as the
+// _go_.o member of an archive.
+func ParseGoObjHeader(data []byte) (*GoObjHeader, error) {
+	if !bytes.HasPrefix(data, []byte("go object ")) {
+		return nil, fmt.Errorf("not a Go object file")
+	}
+	start := bytes.Index(data, []byte("\n!\n"))
+	if start < 0 {
+		return nil, fmt.Errorf("no linker object after the object header")
+	}
+	obj := data[start+len("\n!\n"):]
+
+	const size = len(GoObjMagic) + 8 + 4 + 4*nBlk
+	if !bytes.HasPrefix(obj, []byte(GoObjMagic)) {
+		n := min(len(obj), len(GoObjMagic))
+		return nil, fmt.Errorf("unsupported linker object magic %q", obj[:n])
+	}
+	if len(obj) < size {
+		return nil, fmt.Errorf("truncated linker object header: %d bytes, want %d", len(obj), size)
+	}
+
+	h := &GoObjHeader{Magic: GoObjMagic}
+	off := len(GoObjMagic)
+	copy(h.Fingerprint[:], obj[off:])
+	off += 8
+	h.Flags = binary.LittleEndian.Uint32(obj[off:])
+	off += 4
+	var offsets [nBlk]uint32
+	for i := range offsets {
+		offsets[i] = binary.LittleEndian.Uint32(obj[off:])
+		off += 4
+	}
+
+	for i := 1; i < nBlk; i++ {
+		if offsets[i] < offsets[i-1] {
+			return nil, fmt.Errorf("linker object block %d starts at %d, before block %d at %d", i, offsets[i], i-1, offsets[i-1])
+		}
+	}
+	if int(offsets[blkEnd]) > len(obj) {
+		return nil, fmt.Errorf("truncated linker object: %d bytes, want %d", len(obj), offsets[blkEnd])
+	}
+
+	count := func(blk, size int) int {
+		return int(offsets[blk+1]-offsets[blk]) / size
+	}
+	h.NSyms = count(blkSymdef, goobjSymSize)
+	h.NHashed64Defs = count(blkHashed64def, goobjSymSize)
+	h.NHashedDefs = count(blkHasheddef, goobjSymSize)
+	h.NNonPkgDefs = count(blkNonpkgdef, goobjSymSize)
+	h.NNonPkgRefs = count(blkNonpkgref, goobjSymSize)
+	h.NRelocs = count(blkReloc, goobjRelocSize)
+	return h, nil
+}
+
+// FlagString returns the names of the header's flags.
+func (h *GoObjHeader) FlagString() string {
+	var names []string
+	for _, f := range []struct {
+		flag uint32
+		name string
+	}{
+		{GoObjFlagShared, "shared"},
+		{GoObjFlagFromAssembly, "from assembly"},
+		{GoObjFlagUnlinkable, "unlinkable"},
+		{GoObjFlagStd, "std"},
+	} {
+		if h.Flags&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	if rest := h.Flags &^ (GoObjFlagShared | GoObjFlagFromAssembly | GoObjFlagUnlinkable | GoObjFlagStd); rest != 0 {
+		names = append(names, fmt.Sprintf("%#x", rest))
+	}
+	return strings.Join(names, ", ")
+}
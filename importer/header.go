@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// An ObjectHeader is the text header that cmd/compile writes at the
+// start of __.PKGDEF and of object files, before the export data. It
+// records the configuration that produced the package; the linker and
+// importers refuse to mix objects whose first lines differ.
+type ObjectHeader struct {
+	GOOS        string
+	GOARCH      string
+	Version     string   // toolchain version, such as "go1.25.1"
+	ArchVariant string   // architecture variable setting, such as "GOAMD64=v1", if any
+	Experiments []string // enabled GOEXPERIMENTs
+
+	BuildID string // empty if the compiler was not given one
+	Main    bool   // the package is package main
+
+	// Extra holds header lines that are not recognized.
+	Extra []string
+}
+
+// ParseObjectHeader parses the header at the start of data, which is
+// the contents of __.PKGDEF or a bare object file. The header is a
+// "go object GOOS GOARCH VERSION [K=V] X:EXPERIMENTS" line, optionally
+// followed by a "build id" line and a "main" line, and ends at the
+// first blank line.
+func ParseObjectHeader(data []byte) (*ObjectHeader, error) {
+	line, rest, _ := bytes.Cut(data, []byte("\n"))
+	fields, ok := strings.CutPrefix(string(line), "go object ")
+	if !ok {
+		return nil, fmt.Errorf("not a Go object header: %q", truncate(string(line)))
+	}
+
+	words := strings.Fields(fields)
+	if len(words) < 3 {
+		return nil, fmt.Errorf("malformed Go object header: %q", string(line))
+	}
+	h := &ObjectHeader{GOOS: words[0], GOARCH: words[1], Version: words[2]}
+	for _, word := range words[3:] {
+		switch {
+		case strings.HasPrefix(word, "X:"):
+			if exps := word[len("X:"):]; exps != "" {
+				h.Experiments = strings.Split(exps, ",")
+			}
+		case strings.Contains(word, "=") && h.ArchVariant == "":
+			h.ArchVariant = word
+		default:
+			return nil, fmt.Errorf("malformed Go object header: unexpected %q in %q", word, string(line))
+		}
+	}
+
+	for len(rest) > 0 {
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+		s := string(line)
+		switch {
+		case s == "" || strings.HasPrefix(s, "$$"):
+			return h, nil
+		case strings.HasPrefix(s, "build id "):
+			id, err := strconv.Unquote(s[len("build id "):])
+			if err != nil {
+				return nil, fmt.Errorf("malformed build id line %q: %v", s, err)
+			}
+			h.BuildID = id
+		case s == "main":
+			h.Main = true
+		default:
+			h.Extra = append(h.Extra, s)
+		}
+	}
+	return nil, fmt.Errorf("unterminated Go object header")
+}
+
+// ExtractObjectHeader returns the parsed object header of an archive or
+// bare object file. Raw Unified IR has no header.
+func ExtractObjectHeader(data []byte) (*ObjectHeader, error) {
+	switch c := DetectContainer(data); c {
+	case ArchiveContainer:
+		pkgdef, err := ExtractPKGDEF(data)
+		if err != nil {
+			return nil, err
+		}
+		return ParseObjectHeader(pkgdef)
+	case ObjectContainer:
+		return ParseObjectHeader(data)
+	default:
+		return nil, fmt.Errorf("%v has no object header", c)
+	}
+}
+
+// truncate shortens s for use in error messages.
+func truncate(s string) string {
+	const max = 40
+	if len(s) > max {
+		return s[:max] + "..."
+	}
+	return s
+}
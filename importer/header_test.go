@@ -0,0 +1,173 @@
+package importer_test
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/importer"
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+)
+
+func TestParseObjectHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   *importer.ObjectHeader
+		err    string // substring of the error, if any
+	}{
+		{
+			name:   "minimal",
+			header: "go object linux amd64 go1.23.4\n\n$$B\n",
+			want:   &importer.ObjectHeader{GOOS: "linux", GOARCH: "amd64", Version: "go1.23.4"},
+		},
+		{
+			name:   "full",
+			header: "go object linux amd64 go1.25.1 GOAMD64=v3 X:regabiwrappers,newinliner\nbuild id \"abc/def\"\nmain\n\n$$B\nu",
+			want: &importer.ObjectHeader{
+				GOOS: "linux", GOARCH: "amd64", Version: "go1.25.1",
+				ArchVariant: "GOAMD64=v3",
+				Experiments: []string{"regabiwrappers", "newinliner"},
+				BuildID:     "abc/def",
+				Main:        true,
+			},
+		},
+		{
+			name:   "no experiments",
+			header: "go object darwin arm64 devel X:\n$$B\n",
+			want:   &importer.ObjectHeader{GOOS: "darwin", GOARCH: "arm64", Version: "devel"},
+		},
+		{
+			name:   "unknown lines",
+			header: "go object linux 386 go1.24 GO386=sse2\nsomething new\n\n",
+			want:   &importer.ObjectHeader{GOOS: "linux", GOARCH: "386", Version: "go1.24", ArchVariant: "GO386=sse2", Extra: []string{"something new"}},
+		},
+		{
+			name:   "not a header",
+			header: "!<arch>\n",
+			err:    "not a Go object header",
+		},
+		{
+			name:   "too few fields",
+			header: "go object linux amd64\n\n",
+			err:    "malformed Go object header",
+		},
+		{
+			name:   "unexpected field",
+			header: "go object linux amd64 go1.23 GOAMD64=v1 GOAMD64=v2\n\n",
+			err:    `unexpected "GOAMD64=v2"`,
+		},
+		{
+			name:   "bad build id",
+			header: "go object linux amd64 go1.23\nbuild id abc\n\n",
+			err:    "malformed build id line",
+		},
+		{
+			name:   "unterminated",
+			header: "go object linux amd64 go1.23\nmain\n",
+			err:    "unterminated Go object header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := importer.ParseObjectHeader([]byte(tt.header))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(h, tt.want) {
+				t.Errorf("got %+v, want %+v", h, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractObjectHeader(t *testing.T) {
+	const header = "go object linux amd64 go1.23\n\n$$B\nu"
+	want := &importer.ObjectHeader{GOOS: "linux", GOARCH: "amd64", Version: "go1.23"}
+
+	for _, data := range []string{
+		header,
+		"!<arch>\n" + uirtest.Member("__.PKGDEF", header),
+	} {
+		h, err := importer.ExtractObjectHeader([]byte(data))
+		if err != nil {
+			t.Errorf("ExtractObjectHeader(%q): %v", data, err)
+		} else if !reflect.DeepEqual(h, want) {
+			t.Errorf("ExtractObjectHeader(%q) = %+v, want %+v", data, h, want)
+		}
+	}
+
+	if _, err := importer.ExtractObjectHeader([]byte("u\x00")); err == nil {
+		t.Errorf("ExtractObjectHeader of raw unified IR succeeded")
+	}
+	if _, err := importer.ExtractObjectHeader([]byte("!<arch>\n" + uirtest.Member("_go_.o", header))); err == nil {
+		t.Errorf("ExtractObjectHeader of an archive without __.PKGDEF succeeded")
+	}
+}
+
+// goObj returns a "go object" file holding a linker object with the
+// given fingerprint, flags and block offsets, followed by size bytes.
+func goObj(fingerprint string, flags uint32, offsets []uint32, size int) string {
+	b := []byte("go object linux amd64 go1.23\n\n!\n" + importer.GoObjMagic + fingerprint)
+	b = binary.LittleEndian.AppendUint32(b, flags)
+	for _, off := range offsets {
+		b = binary.LittleEndian.AppendUint32(b, off)
+	}
+	return string(b) + strings.Repeat("\x00", size)
+}
+
+func TestParseGoObjHeader(t *testing.T) {
+	// The header is followed by 19 block offsets: 3 package symbols
+	// of 21 bytes, 1 non-package reference and 2 relocations of 23
+	// bytes.
+	const hdrSize = 8 + 8 + 4 + 4*19
+	offsets := make([]uint32, 19)
+	off := uint32(hdrSize)
+	for i := range offsets {
+		offsets[i] = off
+		switch i {
+		case 3: // Symdef
+			off += 3 * 21
+		case 7: // Nonpkgref
+			off += 21
+		case 14: // Reloc
+			off += 2 * 23
+		}
+	}
+	body := int(off) - hdrSize
+
+	h, err := importer.ParseGoObjHeader([]byte(goObj("12345678", importer.GoObjFlagStd, offsets, body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &importer.GoObjHeader{Magic: importer.GoObjMagic, Flags: importer.GoObjFlagStd, NSyms: 3, NNonPkgRefs: 1, NRelocs: 2}
+	copy(want.Fingerprint[:], "12345678")
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("got %+v, want %+v", h, want)
+	}
+
+	bad := append([]uint32(nil), offsets...)
+	bad[5] = bad[6] + 1
+	for _, tt := range []struct {
+		name, data, err string
+	}{
+		{"not an object", "!<arch>\n", "not a Go object file"},
+		{"no linker object", "go object linux amd64 go1.23\n\n$$B\n", "no linker object"},
+		{"bad magic", "go object linux amd64 go1.23\n\n!\n\x00go119ld", "unsupported linker object magic"},
+		{"truncated header", goObj("12345678", 0, offsets[:4], 0), "truncated linker object header"},
+		{"truncated object", goObj("12345678", 0, offsets, body-1), "truncated linker object:"},
+		{"unordered blocks", goObj("12345678", 0, bad, body), "before block 5"},
+	} {
+		if _, err := importer.ParseGoObjHeader([]byte(tt.data)); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package importer builds go/types packages from unified IR export
+// data, as found in the __.PKGDEF member of archives written by the Go
+// compiler. It only depends on this module's copy of pkgbits, not on
+// the toolchain's internal gcimporter.
+package importer
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// ReadFile reads the archive, object file or raw unified IR in filename and returns
+// the package it describes. See Read.
+func ReadFile(fset *token.FileSet, imports map[string]*types.Package, filename, path string) (*types.Package, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err = ExpandThinArchive(data, filepath.Dir(filename))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	pkg, err := Read(fset, imports, data, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return pkg, nil
+}
+
+// Read returns the package described by data, which is a .a archive,
+// a bare object file, or raw unified IR starting with its 'u' format
+// byte.
+//
+// path is the package's import path; the export data refers to its own
+// package by the empty path. Positions are recorded in fset, and the
+// package and its dependencies are added to imports, which may be nil.
+// Dependencies are only populated with the objects the package refers
+// to, unless they were already present in imports. If the export data
+// is malformed, no packages are added to imports.
+func Read(fset *token.FileSet, imports map[string]*types.Package, data []byte, path string) (pkg *types.Package, err error) {
+	data, _, err = ExtractExportData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := pkgbits.ParsePkgDecoder(path, string(data[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	// Packages are added to a copy of imports and merged on success, so
+	// that a failed read doesn't leave half-initialized packages behind.
+	scratch := maps.Clone(imports)
+	if scratch == nil {
+		scratch = make(map[string]*types.Package)
+	}
+
+	// The decoder reports malformed elements by panicking.
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				pkg, err = nil, fmt.Errorf("import %q: %w", path, e)
+			} else {
+				pkg, err = nil, fmt.Errorf("import %q: %v", path, r)
+			}
+		}
+	}()
+
+	pkg = readUnifiedPackage(fset, nil, scratch, *input)
+	if imports != nil {
+		maps.Copy(imports, scratch)
+	}
+	return pkg, nil
+}
+
+// An Importer implements types.Importer by reading the export data of
+// each imported package from the file named by Lookup.
+type Importer struct {
+	Fset *token.FileSet
+
+	// Packages holds the packages imported so far, indexed by path.
+	// It is allocated on first use if nil.
+	Packages map[string]*types.Package
+
+	// Lookup returns the archive file for the package with the given
+	// import path. Imports fail if it is nil.
+	Lookup func(path string) (filename string, err error)
+}
+
+// Import returns the package with the given import path, reading it
+// unless it was already imported completely.
+func (imp *Importer) Import(path string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	if imp.Packages == nil {
+		imp.Packages = make(map[string]*types.Package)
+	}
+	if pkg := imp.Packages[path]; pkg != nil && pkg.Complete() {
+		return pkg, nil
+	}
+
+	if imp.Lookup == nil {
+		return nil, fmt.Errorf("can't find import %q: no Lookup function", path)
+	}
+	filename, err := imp.Lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	return ReadFile(imp.Fset, imp.Packages, filename, path)
+}
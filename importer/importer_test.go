@@ -0,0 +1,230 @@
+package importer_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/importer"
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// libSource is the package that libPackage writes the export data of,
+// for the client in clientSource to import.
+const libSource = `package lib
+
+type Number interface{ ~int | ~float64 }
+
+type List[T any] struct{ items []T }
+
+func (l *List[T]) Push(v T) { l.items = append(l.items, v) }
+
+func (l List[T]) Len() int { return len(l.items) }
+
+func Sum[T Number](xs []T) (s T) {
+	for _, x := range xs {
+		s += x
+	}
+	return
+}
+
+func Map[T, U any](xs []T, f func(T) U) []U {
+	var out []U
+	for _, x := range xs {
+		out = append(out, f(x))
+	}
+	return out
+}
+
+func Upper(s string) string { return strings.ToUpper(s) }
+`
+
+const clientSource = `package client
+
+import "example.com/m/lib"
+
+var l lib.List[string]
+
+var n = lib.Sum([]float64{1, 2})
+
+var words = lib.Map([]int{1}, func(int) string { return lib.Upper("x") })
+
+func Len() int {
+	l.Push("a")
+	return l.Len()
+}
+`
+
+// libPackage returns the export data of libSource, leaving out the
+// function bodies, which importers don't read.
+func libPackage() []byte {
+	w := uirtest.New(pkgbits.V2, 0, "example.com/m/lib", "lib")
+	file := w.File("lib.go")
+
+	number := w.NewObject(w.Self, "Number", pkgbits.ObjType)
+	list := w.NewObject(w.Self, "List", pkgbits.ObjType)
+	sum := w.NewObject(w.Self, "Sum", pkgbits.ObjFunc)
+	mapObj := w.NewObject(w.Self, "Map", pkgbits.ObjFunc)
+	upper := w.NewObject(w.Self, "Upper", pkgbits.ObjFunc)
+
+	intType := w.Basic(types.Int)
+	stringType := w.Basic(types.String)
+	anyObj := w.NewObject(w.Pkg("builtin", ""), "any", pkgbits.ObjStub)
+	anyType := w.Named(anyObj.Ref())
+
+	number.Pos = file.Pos(3, 6)
+	number.Type = w.Interface(nil, []uirtest.Type{w.Union(
+		uirtest.Term{Tilde: true, Type: intType},
+		uirtest.Term{Tilde: true, Type: w.Basic(types.Float64)},
+	)}, false)
+
+	list.Pos = file.Pos(5, 6)
+	list.TypeParams = []uirtest.TypeParam{{Name: "T", Bound: anyType, Basic: true}}
+	t := list.Derive(w.TypeParam(0))
+	list.Type = list.Derive(w.Struct(uirtest.Field{Name: "items", Type: list.Derive(w.Slice(t))}))
+
+	// Each method has its own receiver type parameters, and so its own
+	// derived types.
+	t = list.Derive(w.TypeParam(0))
+	push := &uirtest.Method{
+		Name:           "Push",
+		RecvTypeParams: []string{"T"},
+		Recv:           uirtest.Param{Name: "l", Type: list.Derive(w.Pointer(list.Derive(w.Named(list.Ref(t)))))},
+		Sig:            &uirtest.Signature{Params: []uirtest.Param{{Name: "v", Type: t}}},
+	}
+	t = list.Derive(w.TypeParam(0))
+	length := &uirtest.Method{
+		Name:           "Len",
+		RecvTypeParams: []string{"T"},
+		Recv:           uirtest.Param{Name: "l", Type: list.Derive(w.Named(list.Ref(t)))},
+		Sig:            &uirtest.Signature{Results: []uirtest.Param{{Type: intType}}},
+	}
+	list.Methods = []*uirtest.Method{push, length}
+
+	sum.TypeParams = []uirtest.TypeParam{{Name: "T", Bound: w.Named(number.Ref())}}
+	t = sum.Derive(w.TypeParam(0))
+	sum.Sig = &uirtest.Signature{
+		Params:  []uirtest.Param{{Name: "xs", Type: sum.Derive(w.Slice(t))}},
+		Results: []uirtest.Param{{Name: "s", Type: t}},
+	}
+
+	mapObj.TypeParams = []uirtest.TypeParam{
+		{Name: "T", Bound: anyType, Basic: true},
+		{Name: "U", Bound: anyType, Basic: true},
+	}
+	t = mapObj.Derive(w.TypeParam(0))
+	u := mapObj.Derive(w.TypeParam(1))
+	mapObj.Sig = &uirtest.Signature{
+		Params: []uirtest.Param{
+			{Name: "xs", Type: mapObj.Derive(w.Slice(t))},
+			{Name: "f", Type: mapObj.Derive(w.Func(&uirtest.Signature{
+				Params:  []uirtest.Param{{Type: t}},
+				Results: []uirtest.Param{{Type: u}},
+			}))},
+		},
+		Results: []uirtest.Param{{Type: mapObj.Derive(w.Slice(u))}},
+	}
+
+	upper.Sig = &uirtest.Signature{
+		Params:  []uirtest.Param{{Name: "s", Type: stringType}},
+		Results: []uirtest.Param{{Type: stringType}},
+	}
+
+	for _, obj := range []*uirtest.Object{number, list, sum, mapObj, upper} {
+		w.Export(obj)
+		obj.Flush()
+	}
+	anyObj.Flush()
+	return w.Bytes()
+}
+
+func TestImportGeneric(t *testing.T) {
+	lib := filepath.Join(t.TempDir(), "lib.uir")
+	if err := os.WriteFile(lib, libPackage(), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	imp := &importer.Importer{
+		Fset: fset,
+		Lookup: func(path string) (string, error) {
+			if path == "example.com/m/lib" {
+				return lib, nil
+			}
+			return "", fmt.Errorf("no export data for %q", path)
+		},
+	}
+	if _, err := imp.Import("example.com/m/lib"); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parser.ParseFile(fset, "client.go", clientSource, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: imp}
+	pkg, err := conf.Check("example.com/m/client", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"l":     "example.com/m/lib.List[string]",
+		"n":     "float64",
+		"words": "[]string",
+	} {
+		if got := pkg.Scope().Lookup(name).Type().String(); got != want {
+			t.Errorf("%s has type %s, want %s", name, got, want)
+		}
+	}
+
+	libPkg := imp.Packages["example.com/m/lib"]
+	for name, want := range map[string]string{
+		"List":  "type example.com/m/lib.List[T any] struct{items []T}",
+		"Sum":   "func example.com/m/lib.Sum[T example.com/m/lib.Number](xs []T) (s T)",
+		"Map":   "func example.com/m/lib.Map[T, U any](xs []T, f func(T) U) []U",
+		"Upper": "func example.com/m/lib.Upper(s string) string",
+	} {
+		if got := libPkg.Scope().Lookup(name).String(); got != want {
+			t.Errorf("lib.%s is %s, want %s", name, got, want)
+		}
+	}
+	list := libPkg.Scope().Lookup("List").Type().(*types.Named)
+	if n := list.NumMethods(); n != 2 {
+		t.Errorf("List has %d methods, want 2", n)
+	}
+}
+
+func TestImporterNoLookup(t *testing.T) {
+	imp := &importer.Importer{Fset: token.NewFileSet()}
+	if _, err := imp.Import("fmt"); err == nil {
+		t.Errorf("Import without Lookup succeeded")
+	}
+	if pkg, err := imp.Import("unsafe"); err != nil || pkg != types.Unsafe {
+		t.Errorf("Import(unsafe) = %v, %v, want types.Unsafe", pkg, err)
+	}
+}
+
+func TestReadMalformedImports(t *testing.T) {
+	w := uirtest.New(pkgbits.V2, 0, "example.com/m/bad", "bad")
+	dep := w.Pkg("example.com/m/dep", "dep")
+	w.Import(dep)
+	v := w.NewObject(w.Self, "V", pkgbits.ObjVar)
+	v.Pos = w.File("bad.go").Pos(3, 5)
+	v.Type = uirtest.Type{Idx: 1000}
+	w.Export(v)
+	v.Flush()
+
+	imports := map[string]*types.Package{"unsafe": types.Unsafe}
+	if _, err := importer.Read(token.NewFileSet(), imports, w.Bytes(), "example.com/m/bad"); err == nil {
+		t.Fatal("Read succeeded with a type index out of range")
+	}
+	if len(imports) != 1 {
+		t.Errorf("imports = %v after a failed Read, want only unsafe", imports)
+	}
+}
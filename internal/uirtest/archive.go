@@ -0,0 +1,36 @@
+package uirtest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Header is an object header, as cmd/compile writes at the start of
+// __.PKGDEF and of object files.
+const Header = "go object linux amd64 go1.23.4 X:none\nbuild id \"abc/def\"\n"
+
+// Archive returns a .a archive laid out as cmd/compile writes it: the
+// export data in __.PKGDEF, after header, then the object file _go_.o,
+// whose contents after its header are obj.
+func Archive(header string, data []byte, obj string) []byte {
+	var b strings.Builder
+	b.WriteString("!<arch>\n")
+	b.WriteString(Member("__.PKGDEF", header+"\n$$B\n"+string(data)+"\n$$\n"))
+	b.WriteString(Member("_go_.o", header+"\n!\n"+obj))
+	return []byte(b.String())
+}
+
+// ObjectFile returns an object file as "go tool compile" writes it without
+// -pack: header, the export data, then the object data proper.
+func ObjectFile(header string, data []byte, obj string) []byte {
+	return []byte(header + "\n$$B\n" + string(data) + "\n$$\n\n!\n" + obj)
+}
+
+// Member returns an archive member with its header and padding.
+func Member(name, data string) string {
+	s := fmt.Sprintf("%-16s%-12d%-6d%-6d%-8o%-10d`\n", name, 0, 0, 0, 0o644, len(data)) + data
+	if len(data)%2 == 1 {
+		s += "\n"
+	}
+	return s
+}
@@ -0,0 +1,322 @@
+package uirtest
+
+import (
+	"go/constant"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// An Object is an object declared at package level. Its elements share
+// an index in SectionName, SectionObjDict, SectionObj and
+// SectionObjExt; NewObject reserves them, so that types can refer to
+// the object before Flush writes it. Which fields are written depends
+// on Tag.
+type Object struct {
+	w                    *Writer
+	name, dict, obj, ext *pkgbits.Encoder
+
+	Idx  pkgbits.Index
+	Pkg  pkgbits.Index
+	Name string
+	Tag  pkgbits.CodeObj
+
+	Pos     Pos
+	Type    Type           // ObjAlias, ObjConst, ObjVar; underlying type for ObjType
+	Value   constant.Value // ObjConst
+	Sig     *Signature     // ObjFunc
+	Methods []*Method      // ObjType
+
+	// The dictionary. Implicits is the number of implicit type
+	// parameters, which precede TypeParams.
+	Implicits   int
+	TypeParams  []TypeParam
+	derived     []pkgbits.Index
+	MethodExprs []MethodExpr
+	Subdicts    []ObjRef
+	RTypes      []Type
+	Itabs       [][2]Type // type, interface
+
+	// The extension data.
+	FuncExt *FuncExt // ObjFunc
+	TypeExt TypeExt  // ObjType
+	Link    Link     // ObjVar
+}
+
+// A TypeParam is an explicit type parameter.
+type TypeParam struct {
+	Pos   Pos
+	Name  string
+	Bound Type
+
+	// Basic reports whether the constraint is a basic interface.
+	Basic bool
+}
+
+// A Method is a method declared on a defined type. RecvTypeParams
+// names the receiver type's parameters, which methods may rename.
+type Method struct {
+	Pos            Pos
+	Name           string
+	RecvTypeParams []string
+	Recv           Param
+	Sig            *Signature
+	Ext            *FuncExt
+}
+
+// A MethodExpr is a method expression on a type parameter in a runtime
+// dictionary.
+type MethodExpr struct {
+	TypeParam int
+	Method    string
+}
+
+// An ObjRef refers to an object, instantiated with TypeArgs if it is
+// generic.
+type ObjRef struct {
+	Idx      pkgbits.Index
+	TypeArgs []Type
+}
+
+// A FuncExt is the extension data of a function or method. If Body is
+// set, the function is generic and importers compile it from the body
+// instead of the compiler's own analysis results, which are left out.
+type FuncExt struct {
+	Pragma int
+	Link   Link
+	Body   *pkgbits.Index
+
+	ABI     uint64
+	Escapes []string // one per receiver and parameter
+	Inline  *Inline  // nil if not inlinable
+}
+
+// An Inline describes an inlinable function.
+type Inline struct {
+	Cost            int
+	CanDelayResults bool
+}
+
+// A TypeExt is the extension data of a defined type.
+type TypeExt struct {
+	Pragma          int
+	TypeSym, PtrSym int64
+}
+
+// A Link tells the linker how to find an object's symbol: by SymIdx,
+// or if that is negative, by a //go:linkname directive.
+type Link struct {
+	SymIdx   int64
+	Linkname string
+	Std      bool
+}
+
+// NewObject reserves the elements of a new object named name, declared
+// in the package pkg.
+func (w *Writer) NewObject(pkg pkgbits.Index, name string, tag pkgbits.CodeObj) *Object {
+	o := &Object{
+		w:    w,
+		obj:  w.pw.NewEncoder(pkgbits.SectionObj, pkgbits.SyncObject1),
+		ext:  w.pw.NewEncoder(pkgbits.SectionObjExt, pkgbits.SyncObject1),
+		name: w.pw.NewEncoder(pkgbits.SectionName, pkgbits.SyncObject1),
+		dict: w.pw.NewEncoder(pkgbits.SectionObjDict, pkgbits.SyncObject1),
+		Pkg:  pkg,
+		Name: name,
+		Tag:  tag,
+	}
+	o.Idx = o.obj.Idx
+	return o
+}
+
+// Ref returns a reference to o, instantiated with targs.
+func (o *Object) Ref(targs ...Type) ObjRef {
+	return ObjRef{Idx: o.Idx, TypeArgs: targs}
+}
+
+// Derive adds t, which depends on o's type parameters, to o's derived
+// types and returns a reference to it for use within o.
+func (o *Object) Derive(t Type) Type {
+	o.derived = append(o.derived, t.Idx)
+	return Type{Idx: pkgbits.Index(len(o.derived) - 1), Derived: true}
+}
+
+// Flush writes o's elements.
+func (o *Object) Flush() {
+	e := o.w.encoder(o.name)
+	e.QualifiedIdent(o.Pkg, o.Name)
+	e.Code(o.Tag)
+	e.Flush()
+
+	o.flushDict()
+	if o.Tag == pkgbits.ObjStub {
+		o.obj.Flush()
+		o.ext.Flush()
+		return
+	}
+	o.flushObj()
+	o.flushExt()
+}
+
+func (o *Object) flushDict() {
+	e := o.w.encoder(o.dict)
+	e.Len(o.Implicits)
+	e.Len(len(o.TypeParams))
+	for _, tp := range o.TypeParams {
+		e.Type(tp.Bound)
+	}
+	e.Len(len(o.derived))
+	for _, idx := range o.derived {
+		e.Reloc(pkgbits.SectionType, idx)
+		if e.Version().Has(pkgbits.DerivedInfoNeeded) {
+			e.Bool(false)
+		}
+	}
+
+	for range o.Implicits {
+		e.Bool(false)
+	}
+	for _, tp := range o.TypeParams {
+		e.Bool(tp.Basic)
+	}
+	e.Len(len(o.MethodExprs))
+	for _, m := range o.MethodExprs {
+		e.Len(m.TypeParam)
+		e.Selector(m.Method)
+	}
+	e.Len(len(o.Subdicts))
+	for _, ref := range o.Subdicts {
+		e.Obj(ref)
+	}
+	e.Len(len(o.RTypes))
+	for _, t := range o.RTypes {
+		e.Type(t)
+	}
+	e.Len(len(o.Itabs))
+	for _, itab := range o.Itabs {
+		e.Type(itab[0])
+		e.Type(itab[1])
+	}
+	e.Flush()
+}
+
+func (o *Object) flushObj() {
+	e := o.w.encoder(o.obj)
+	switch o.Tag {
+	case pkgbits.ObjAlias:
+		e.Pos(o.Pos)
+		if e.Version().Has(pkgbits.AliasTypeParamNames) {
+			o.typeParamNames(e)
+		}
+		e.Type(o.Type)
+
+	case pkgbits.ObjConst:
+		e.Pos(o.Pos)
+		e.Type(o.Type)
+		e.Value(o.Value)
+
+	case pkgbits.ObjFunc:
+		e.Pos(o.Pos)
+		o.typeParamNames(e)
+		e.Signature(o.Sig)
+		e.Pos(o.Pos) // where the body starts; unused by readers
+
+	case pkgbits.ObjType:
+		e.Pos(o.Pos)
+		o.typeParamNames(e)
+		e.Type(o.Type)
+		e.Len(len(o.Methods))
+		for _, m := range o.Methods {
+			e.Sync(pkgbits.SyncMethod)
+			e.Pos(m.Pos)
+			e.Selector(m.Name)
+			e.Sync(pkgbits.SyncTypeParamNames)
+			for _, name := range m.RecvTypeParams {
+				e.Pos(m.Pos)
+				e.LocalIdent(name)
+			}
+			e.Param(m.Recv)
+			e.Signature(m.Sig)
+			e.Pos(m.Pos)
+		}
+
+	case pkgbits.ObjVar:
+		e.Pos(o.Pos)
+		e.Type(o.Type)
+	}
+	e.Flush()
+}
+
+func (o *Object) typeParamNames(e *Encoder) {
+	e.Sync(pkgbits.SyncTypeParamNames)
+	for _, tp := range o.TypeParams {
+		e.Pos(tp.Pos)
+		e.LocalIdent(tp.Name)
+	}
+}
+
+func (o *Object) flushExt() {
+	e := o.w.encoder(o.ext)
+	switch o.Tag {
+	case pkgbits.ObjFunc:
+		e.funcExt(o.FuncExt, len(o.Sig.Params))
+
+	case pkgbits.ObjType:
+		e.Sync(pkgbits.SyncTypeExt)
+		e.pragma(o.TypeExt.Pragma)
+		e.Int64(o.TypeExt.TypeSym)
+		e.Int64(o.TypeExt.PtrSym)
+		for _, m := range o.Methods {
+			e.funcExt(m.Ext, 1+len(m.Sig.Params))
+		}
+
+	case pkgbits.ObjVar:
+		e.Sync(pkgbits.SyncVarExt)
+		e.link(o.Link)
+	}
+	e.Flush()
+}
+
+// funcExt writes the extension data of a function with nparams
+// receiver and parameter values. A nil ext is written as a function
+// with no directives or analysis results.
+func (e *Encoder) funcExt(ext *FuncExt, nparams int) {
+	if ext == nil {
+		ext = new(FuncExt)
+	}
+	e.Sync(pkgbits.SyncFuncExt)
+	e.pragma(ext.Pragma)
+	e.link(ext.Link)
+
+	if ext.Body != nil {
+		e.Bool(false)
+		e.Reloc(pkgbits.SectionBody, *ext.Body)
+	} else {
+		e.Bool(true)
+		e.Uint64(ext.ABI)
+		for i := range nparams {
+			var note string
+			if i < len(ext.Escapes) {
+				note = ext.Escapes[i]
+			}
+			e.String(note)
+		}
+		if e.Bool(ext.Inline != nil) {
+			e.Len(ext.Inline.Cost)
+			e.Bool(ext.Inline.CanDelayResults)
+		}
+	}
+	e.Sync(pkgbits.SyncEOF)
+}
+
+func (e *Encoder) pragma(flags int) {
+	e.Sync(pkgbits.SyncPragma)
+	e.Int(flags)
+}
+
+func (e *Encoder) link(l Link) {
+	e.Sync(pkgbits.SyncLinkname)
+	e.Int64(l.SymIdx)
+	if l.SymIdx < 0 {
+		e.String(l.Linkname)
+		e.Bool(l.Std)
+	}
+}
@@ -0,0 +1,175 @@
+package uirtest
+
+import (
+	"go/types"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// A Type is a type reference: a SectionType element or, if Derived,
+// the derived type with index Idx in the dictionary of the object
+// being written. See Object.Derive.
+type Type struct {
+	Idx     pkgbits.Index
+	Derived bool
+}
+
+// A Signature is a function signature.
+type Signature struct {
+	Params, Results []Param
+	Variadic        bool
+}
+
+// A Param is a function parameter, result or receiver. Names are
+// empty for unnamed parameters.
+type Param struct {
+	Pos  Pos
+	Name string
+	Type Type
+}
+
+// A Field is a struct field.
+type Field struct {
+	Pos      Pos
+	Name     string
+	Type     Type
+	Tag      string
+	Embedded bool
+}
+
+// An IfaceMethod is an interface method.
+type IfaceMethod struct {
+	Pos  Pos
+	Name string
+	Sig  *Signature
+}
+
+// A Term is a union term.
+type Term struct {
+	Tilde bool
+	Type  Type
+}
+
+// newType returns an Encoder for a new SectionType element of the
+// given kind.
+func (w *Writer) newType(code pkgbits.CodeType) *Encoder {
+	e := w.encoder(w.pw.NewEncoder(pkgbits.SectionType, pkgbits.SyncTypeIdx))
+	e.Code(code)
+	return e
+}
+
+// flushType writes a SectionType element and returns a reference to
+// it.
+func flushType(e *Encoder) Type {
+	return Type{Idx: e.Flush()}
+}
+
+// Basic returns the predeclared type of the given kind, writing it
+// first if needed.
+func (w *Writer) Basic(kind types.BasicKind) Type {
+	if t, ok := w.basics[int(kind)]; ok {
+		return t
+	}
+	e := w.newType(pkgbits.TypeBasic)
+	e.Len(int(kind))
+	w.basics[int(kind)] = flushType(e)
+	return w.basics[int(kind)]
+}
+
+// Named writes a defined type, or an instance of a generic one.
+func (w *Writer) Named(ref ObjRef) Type {
+	e := w.newType(pkgbits.TypeNamed)
+	e.Obj(ref)
+	return flushType(e)
+}
+
+// TypeParam writes the type parameter with the given index in the
+// dictionary of the object being written, implicit ones first.
+func (w *Writer) TypeParam(index int) Type {
+	e := w.newType(pkgbits.TypeTypeParam)
+	e.Len(index)
+	return flushType(e)
+}
+
+func (w *Writer) Array(n uint64, elem Type) Type {
+	e := w.newType(pkgbits.TypeArray)
+	e.Uint64(n)
+	e.Type(elem)
+	return flushType(e)
+}
+
+func (w *Writer) Chan(dir types.ChanDir, elem Type) Type {
+	e := w.newType(pkgbits.TypeChan)
+	e.Len(int(dir))
+	e.Type(elem)
+	return flushType(e)
+}
+
+func (w *Writer) Map(key, elem Type) Type {
+	e := w.newType(pkgbits.TypeMap)
+	e.Type(key)
+	e.Type(elem)
+	return flushType(e)
+}
+
+func (w *Writer) Pointer(elem Type) Type {
+	e := w.newType(pkgbits.TypePointer)
+	e.Type(elem)
+	return flushType(e)
+}
+
+func (w *Writer) Slice(elem Type) Type {
+	e := w.newType(pkgbits.TypeSlice)
+	e.Type(elem)
+	return flushType(e)
+}
+
+func (w *Writer) Func(sig *Signature) Type {
+	e := w.newType(pkgbits.TypeSignature)
+	e.Signature(sig)
+	return flushType(e)
+}
+
+func (w *Writer) Struct(fields ...Field) Type {
+	e := w.newType(pkgbits.TypeStruct)
+	e.Len(len(fields))
+	for _, f := range fields {
+		e.Pos(f.Pos)
+		e.Selector(f.Name)
+		e.Type(f.Type)
+		e.String(f.Tag)
+		e.Bool(f.Embedded)
+	}
+	return flushType(e)
+}
+
+// Interface writes an interface type. implicit is only written for
+// interfaces with a single embedded type and no methods, and marks the
+// interface of a constraint such as ~int.
+func (w *Writer) Interface(methods []IfaceMethod, embeddeds []Type, implicit bool) Type {
+	e := w.newType(pkgbits.TypeInterface)
+	e.Len(len(methods))
+	e.Len(len(embeddeds))
+	if len(methods) == 0 && len(embeddeds) == 1 {
+		e.Bool(implicit)
+	}
+	for _, m := range methods {
+		e.Pos(m.Pos)
+		e.Selector(m.Name)
+		e.Signature(m.Sig)
+	}
+	for _, t := range embeddeds {
+		e.Type(t)
+	}
+	return flushType(e)
+}
+
+func (w *Writer) Union(terms ...Term) Type {
+	e := w.newType(pkgbits.TypeUnion)
+	e.Len(len(terms))
+	for _, t := range terms {
+		e.Bool(t.Tilde)
+		e.Type(t.Type)
+	}
+	return flushType(e)
+}
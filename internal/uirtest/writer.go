@@ -0,0 +1,294 @@
+// Package uirtest writes unified IR export data for tests. The
+// elements are laid out as cmd/compile's noder writes them, but tests
+// describe their contents directly, so they don't depend on the
+// toolchain that runs them.
+package uirtest
+
+import (
+	"bytes"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// A Writer writes the export data of one package.
+type Writer struct {
+	pw pkgbits.PkgEncoder
+
+	public, private *pkgbits.Encoder
+
+	// Self is the SectionPkg index of the package being written.
+	Self pkgbits.Index
+	self *pkgbits.Encoder
+
+	path, name string
+	imports    []pkgbits.Index
+	pkgs       map[string]pkgbits.Index
+	files      map[string]*PosBase
+	basics     map[int]Type
+
+	// HasInit is the public root's legacy flag, written before V2.
+	HasInit bool
+
+	// Inittask is the private root's flag reporting whether the
+	// package has a .inittask symbol.
+	Inittask bool
+
+	exports []*Object
+	bodies  []inlineBody
+}
+
+// An inlineBody is a function body listed by the private root.
+type inlineBody struct {
+	path, name string
+	idx        pkgbits.Index
+}
+
+// New returns a Writer for the package with the given import path and
+// name. An empty path is written as older toolchains do, leaving the
+// path to the importer. syncFrames is as for pkgbits.NewPkgEncoder: -1
+// omits sync markers, as the compiler does by default.
+func New(version pkgbits.Version, syncFrames int, path, name string) *Writer {
+	w := &Writer{
+		pw:     pkgbits.NewPkgEncoder(version, syncFrames),
+		path:   path,
+		name:   name,
+		pkgs:   make(map[string]pkgbits.Index),
+		files:  make(map[string]*PosBase),
+		basics: make(map[int]Type),
+	}
+	w.public = w.pw.NewEncoder(pkgbits.SectionMeta, pkgbits.SyncPublic)
+	w.private = w.pw.NewEncoder(pkgbits.SectionMeta, pkgbits.SyncPrivate)
+	w.self = w.pw.NewEncoder(pkgbits.SectionPkg, pkgbits.SyncPkgDef)
+	w.Self = w.self.Idx
+	w.pkgs[path] = w.Self
+	return w
+}
+
+// Export adds obj to the objects that the public root lists, which
+// importers read in order.
+func (w *Writer) Export(obj *Object) {
+	w.exports = append(w.exports, obj)
+}
+
+// InlineBody lists the SectionBody element idx in the private root, as
+// the body of the function with linker symbol path.name.
+func (w *Writer) InlineBody(path, name string, idx pkgbits.Index) {
+	w.bodies = append(w.bodies, inlineBody{path, name, idx})
+}
+
+// Bytes writes the roots and returns the export data, starting with
+// the 'u' byte that marks it as unified IR. It must only be called
+// once.
+func (w *Writer) Bytes() []byte {
+	w.self.String(w.path)
+	w.self.String(w.name)
+	w.self.Len(len(w.imports))
+	for _, idx := range w.imports {
+		w.self.Sync(pkgbits.SyncPkg)
+		w.self.Reloc(pkgbits.SectionPkg, idx)
+	}
+	w.self.Flush()
+
+	e := w.encoder(w.public)
+	e.Pkg(w.Self)
+	if e.Version().Has(pkgbits.HasInit) {
+		e.Bool(w.HasInit)
+	}
+	e.Len(len(w.exports))
+	for _, obj := range w.exports {
+		e.Obj(obj.Ref())
+	}
+	e.Sync(pkgbits.SyncEOF)
+	e.Flush()
+
+	e = w.encoder(w.private)
+	e.Bool(w.Inittask)
+	e.Len(len(w.bodies))
+	for _, b := range w.bodies {
+		e.String(b.path)
+		e.String(b.name)
+		e.Reloc(pkgbits.SectionBody, b.idx)
+	}
+	e.Sync(pkgbits.SyncEOF)
+	e.Flush()
+
+	var buf bytes.Buffer
+	buf.WriteByte('u')
+	w.pw.DumpTo(&buf)
+	return buf.Bytes()
+}
+
+// @@@ Packages
+
+// Pkg returns the SectionPkg index of the package with the given path,
+// writing it first if needed. The package itself is Self. Packages
+// other than builtin and unsafe are written with their name and
+// imports.
+func (w *Writer) Pkg(path, name string, imports ...pkgbits.Index) pkgbits.Index {
+	if idx, ok := w.pkgs[path]; ok {
+		return idx
+	}
+	e := w.pw.NewEncoder(pkgbits.SectionPkg, pkgbits.SyncPkgDef)
+	e.String(path)
+	if path != "builtin" && path != "unsafe" {
+		e.String(name)
+		e.Len(len(imports))
+		for _, idx := range imports {
+			e.Sync(pkgbits.SyncPkg)
+			e.Reloc(pkgbits.SectionPkg, idx)
+		}
+	}
+	w.pkgs[path] = e.Flush()
+	return w.pkgs[path]
+}
+
+// Import adds the package idx to the imports of the package itself.
+func (w *Writer) Import(idx pkgbits.Index) {
+	w.imports = append(w.imports, idx)
+}
+
+// @@@ Positions
+
+// A PosBase is a SectionPosBase element: a source file, or a //line
+// directive within one.
+type PosBase struct {
+	Idx pkgbits.Index
+}
+
+// A Pos is a source position. The zero Pos is unknown.
+type Pos struct {
+	Base      *PosBase
+	Line, Col uint
+}
+
+// File returns the position base of the named source file, writing it
+// first if needed.
+func (w *Writer) File(filename string) *PosBase {
+	if b, ok := w.files[filename]; ok {
+		return b
+	}
+	e := w.encoder(w.pw.NewEncoder(pkgbits.SectionPosBase, pkgbits.SyncPosBase))
+	e.String(filename)
+	e.Bool(true)
+	b := &PosBase{Idx: e.Flush()}
+	w.files[filename] = b
+	return b
+}
+
+// LineBase writes the position base of a //line directive at pos,
+// which makes the position following it filename:line:col. A column of
+// zero leaves columns unknown.
+func (w *Writer) LineBase(pos Pos, filename string, line, col uint) *PosBase {
+	e := w.encoder(w.pw.NewEncoder(pkgbits.SectionPosBase, pkgbits.SyncPosBase))
+	e.String(filename)
+	e.Bool(false)
+	e.Pos(pos)
+	e.Uint(line)
+	e.Uint(col)
+	return &PosBase{Idx: e.Flush()}
+}
+
+// Pos returns the position line:col relative to b.
+func (b *PosBase) Pos(line, col uint) Pos {
+	return Pos{Base: b, Line: line, Col: col}
+}
+
+// @@@ Element encoding
+
+// An Encoder writes one element's bitstream, with the helpers that
+// cmd/compile's writer uses for references to other elements.
+type Encoder struct {
+	*pkgbits.Encoder
+	w *Writer
+}
+
+func (w *Writer) encoder(e *pkgbits.Encoder) *Encoder {
+	return &Encoder{Encoder: e, w: w}
+}
+
+// NewBody returns an Encoder for a new SectionBody element, which the
+// test writes as the compiler's writer would.
+func (w *Writer) NewBody() *Encoder {
+	return w.encoder(w.pw.NewEncoder(pkgbits.SectionBody, pkgbits.SyncFuncBody))
+}
+
+// SyncMarkers reports whether the export data has sync markers.
+func (e *Encoder) SyncMarkers() bool { return e.w.pw.SyncMarkers() }
+
+// Pos writes a position.
+func (e *Encoder) Pos(p Pos) {
+	e.Sync(pkgbits.SyncPos)
+	if !e.Bool(p.Base != nil) {
+		return
+	}
+	e.Reloc(pkgbits.SectionPosBase, p.Base.Idx)
+	e.Uint(p.Line)
+	e.Uint(p.Col)
+}
+
+// Pkg writes a reference to the package idx.
+func (e *Encoder) Pkg(idx pkgbits.Index) {
+	e.Sync(pkgbits.SyncPkg)
+	e.Reloc(pkgbits.SectionPkg, idx)
+}
+
+// QualifiedIdent, LocalIdent and Selector write a name declared in the
+// package pkg.
+func (e *Encoder) QualifiedIdent(pkg pkgbits.Index, name string) {
+	e.ident(pkgbits.SyncSym, pkg, name)
+}
+func (e *Encoder) LocalIdent(name string) { e.ident(pkgbits.SyncLocalIdent, e.w.Self, name) }
+func (e *Encoder) Selector(name string)   { e.ident(pkgbits.SyncSelector, e.w.Self, name) }
+
+func (e *Encoder) ident(marker pkgbits.SyncMarker, pkg pkgbits.Index, name string) {
+	e.Sync(marker)
+	e.Pkg(pkg)
+	e.String(name)
+}
+
+// Type writes a type reference.
+func (e *Encoder) Type(t Type) {
+	e.Sync(pkgbits.SyncType)
+	if e.Bool(t.Derived) {
+		e.Len(int(t.Idx))
+	} else {
+		e.Reloc(pkgbits.SectionType, t.Idx)
+	}
+}
+
+// Obj writes an object reference.
+func (e *Encoder) Obj(ref ObjRef) {
+	e.Sync(pkgbits.SyncObject)
+	if e.Version().Has(pkgbits.DerivedFuncInstance) {
+		e.Bool(false)
+	}
+	e.Reloc(pkgbits.SectionObj, ref.Idx)
+	e.Len(len(ref.TypeArgs))
+	for _, t := range ref.TypeArgs {
+		e.Type(t)
+	}
+}
+
+// Signature writes a function signature.
+func (e *Encoder) Signature(sig *Signature) {
+	e.Sync(pkgbits.SyncSignature)
+	e.params(sig.Params)
+	e.params(sig.Results)
+	e.Bool(sig.Variadic)
+}
+
+func (e *Encoder) params(params []Param) {
+	e.Sync(pkgbits.SyncParams)
+	e.Len(len(params))
+	for _, p := range params {
+		e.Param(p)
+	}
+}
+
+// Param writes a parameter, result or receiver.
+func (e *Encoder) Param(p Param) {
+	e.Sync(pkgbits.SyncParam)
+	e.Pos(p.Pos)
+	e.LocalIdent(p.Name)
+	e.Type(p.Type)
+}
@@ -3,22 +3,38 @@
 package main
 
 import (
-	"bytes"
-	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"strings"
+	"path/filepath"
+	"slices"
 
+	"github.com/jespino/unified-ir-reader/importer"
 	"github.com/jespino/unified-ir-reader/pkgbits"
 )
 
 func main() {
 	// Define flags
 	limit := flag.Int("limit", 0, "Limit the number of entries shown per section (0 = show all)")
+	format := flag.String("format", "text", "Output `format`: text, json or html")
+	importcfgFile := flag.String("importcfg", "", "Read package archive mappings from the importcfg `file`")
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] <archive.a>\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Decodes and displays the contents of __.PKGDEF from a Go archive file\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <file|package>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s validate <file|package>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s members <file.a|package>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s scan [-o file] [dir]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s graph [-from elem] [-depth n] <file|package>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s dump -element elem <file|package>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s explain <file|package>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Decodes and displays the export data of a Go archive, object file or raw\n")
+		fmt.Fprintf(os.Stderr, "Unified IR file, checks that the export data of each file is well formed,\n")
+		fmt.Fprintf(os.Stderr, "lists the members of an archive, indexes every file with export data in a\n")
+		fmt.Fprintf(os.Stderr, "directory (see \"scan -h\"), graphs the references between elements (see\n")
+		fmt.Fprintf(os.Stderr, "\"graph -h\"), dumps the bytes of an element (see \"dump -h\"), or walks the\n")
+		fmt.Fprintf(os.Stderr, "export data header field by field (\"explain\"). Arguments that are not files\n")
+		fmt.Fprintf(os.Stderr, "are import paths or patterns, whose export data is found with\n")
+		fmt.Fprintf(os.Stderr, "\"go list -export\"\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 	}
@@ -29,297 +45,258 @@ func main() {
 		os.Exit(1)
 	}
 
-	archivePath := flag.Arg(0)
-
-	// Read the archive file
-	data, err := os.ReadFile(archivePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+	if *format != "text" && *format != "json" && *format != "html" {
+		fmt.Fprintf(os.Stderr, "Error unknown format %q (want text, json or html)\n", *format)
 		os.Exit(1)
 	}
 
-	// Extract __.PKGDEF from the archive
-	pkgdefData, err := extractPKGDEF(data)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error extracting __.PKGDEF: %v\n", err)
-		os.Exit(1)
+	var cfg *importCfg
+	if *importcfgFile != "" {
+		var err error
+		if cfg, err = readImportCfg(*importcfgFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading importcfg: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	// Extract the unified IR data
-	uirData, err := extractUnifiedIR(pkgdefData)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error extracting Unified IR: %v\n", err)
-		os.Exit(1)
+	if flag.Arg(0) == "scan" {
+		if err := runScan(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Show binary format information
-	if err := showDetailedFormat(uirData, *limit); err != nil {
-		fmt.Fprintf(os.Stderr, "Error decoding format: %v\n", err)
-		os.Exit(1)
+	if flag.Arg(0) == "graph" {
+		if err := runGraph(flag.Args()[1:], cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
-}
 
-// extractPKGDEF extracts the __.PKGDEF section from a .a archive
-func extractPKGDEF(data []byte) ([]byte, error) {
-	// Check for archive magic
-	if !bytes.HasPrefix(data, []byte("!<arch>\n")) {
-		return nil, fmt.Errorf("not a valid archive file")
+	if flag.Arg(0) == "dump" {
+		if err := runDump(flag.Args()[1:], cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	offset := 8 // Skip "!<arch>\n"
-
-	for offset < len(data) {
-		// Each archive entry has a 60-byte header
-		if offset+60 > len(data) {
-			break
+	if flag.Arg(0) == "explain" {
+		if err := runExplain(flag.Args()[1:], cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+			os.Exit(1)
 		}
+		return
+	}
 
-		header := data[offset : offset+60]
-
-		// Parse the file name (16 bytes)
-		name := strings.TrimSpace(string(header[0:16]))
-
-		// Parse the file size (10 bytes, decimal ASCII)
-		sizeStr := strings.TrimSpace(string(header[48:58]))
-		var size int
-		fmt.Sscanf(sizeStr, "%d", &size)
-
-		offset += 60
-
-		// Check if this is the __.PKGDEF entry
-		if name == "__.PKGDEF" {
-			if offset+size > len(data) {
-				return nil, fmt.Errorf("truncated archive")
-			}
-			return data[offset : offset+size], nil
+	if flag.Arg(0) == "validate" {
+		if flag.NArg() < 2 {
+			fmt.Fprintf(os.Stderr, "Usage: %s validate <file|package>...\n", os.Args[0])
+			os.Exit(2)
 		}
-
-		// Move to next entry (entries are 2-byte aligned)
-		offset += size
-		if size%2 == 1 {
-			offset++ // Skip padding byte
+		inputs, err := resolveInputs(flag.Args()[1:], cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+			os.Exit(1)
+		}
+		ok := true
+		for _, in := range inputs {
+			ok = runValidate(in, cfg) && ok
 		}
+		if !ok {
+			os.Exit(1)
+		}
+		return
 	}
 
-	return nil, fmt.Errorf("__.PKGDEF not found in archive")
-}
-
-// extractUnifiedIR extracts the Unified IR data from __.PKGDEF content
-func extractUnifiedIR(pkgdefData []byte) ([]byte, error) {
-	// The format is:
-	// \n$$B\n
-	// u<unified-ir-data>
-	// \n$$\n
-
-	start := bytes.Index(pkgdefData, []byte("\n$$B\n"))
-	if start == -1 {
-		return nil, fmt.Errorf("could not find export data start marker")
+	if flag.Arg(0) == "members" {
+		if flag.NArg() != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: %s members <file.a|package>\n", os.Args[0])
+			os.Exit(2)
+		}
+		inputs, err := resolveInputs(flag.Args()[1:], cfg)
+		if err == nil && len(inputs) != 1 {
+			err = fmt.Errorf("%s matches %d packages, want one", flag.Arg(1), len(inputs))
+		}
+		if err == nil {
+			err = runMembers(inputs[0].filename)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
-	start += 5 // Skip "\n$$B\n"
 
-	end := bytes.Index(pkgdefData[start:], []byte("\n$$\n"))
-	if end == -1 {
-		return nil, fmt.Errorf("could not find export data end marker")
+	inputs, err := resolveInputs(flag.Args(), cfg)
+	if err == nil && *format == "html" && len(inputs) != 1 {
+		err = fmt.Errorf("HTML reports are for one package, but the arguments match %d", len(inputs))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(1)
 	}
+	// A failure doesn't stop the remaining inputs from being shown.
+	failed := false
+	for i, in := range inputs {
+		if i > 0 {
+			fmt.Println()
+		}
+		prefix := ""
+		if len(inputs) > 1 {
+			prefix = in.String() + ": "
+		}
 
-	exportData := pkgdefData[start : start+end]
+		f, err := readExportFile(in)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error %s%v\n", prefix, err)
+			failed = true
+			continue
+		}
 
-	// Check for 'u' prefix indicating unified IR
-	if len(exportData) == 0 || exportData[0] != 'u' {
-		return nil, fmt.Errorf("not unified IR format (expected 'u' prefix)")
+		// Show binary format information
+		if err := showDetailedFormat(f, cfg, *format, *limit); err != nil {
+			var desync *pkgbits.DesyncError
+			if errors.As(err, &desync) {
+				fmt.Fprintf(os.Stderr, "Error decoding format: %s\n%s", prefix, desync.Report())
+			} else {
+				fmt.Fprintf(os.Stderr, "Error decoding format: %s%v\n", prefix, err)
+			}
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
 	}
-
-	// Return the complete export data including the 'u' prefix
-	return exportData, nil
 }
 
-// showDetailedFormat shows detailed binary format information
-func showDetailedFormat(exportData []byte, limit int) error {
-	// Skip the 'u' prefix
-	decoder := pkgbits.NewPkgDecoder("", string(exportData[1:]))
-
-	fmt.Println("╔═══════════════════════════════════════════════════════════════╗")
-	fmt.Println("║                   Unified IR Binary Format                    ║")
-	fmt.Println("╚═══════════════════════════════════════════════════════════════╝")
-	fmt.Println()
+// An exportFile is the export data read from a file.
+type exportFile struct {
+	input
+	container importer.Container
+	header    *importer.ObjectHeader // nil for raw Unified IR
+	uirData   []byte                 // including the 'u' prefix
 
-	// Show format metadata
-	fmt.Println("=== Format Metadata ===")
-	fmt.Printf("Sync Markers: %v\n", decoder.SyncMarkers())
-	fmt.Printf("Total Elements: %d\n", decoder.TotalElems())
-
-	fp := decoder.Fingerprint()
-	fmt.Printf("Fingerprint: %s\n", hex.EncodeToString(fp[:]))
-	fmt.Println()
+	// archiveErr is a defect in the archive's members after
+	// __.PKGDEF, which doesn't keep the export data from being read.
+	archiveErr error
+}
 
-	// Show section statistics
-	fmt.Println("=== Section Statistics ===")
-	sections := []struct {
-		kind pkgbits.SectionKind
-		name string
-	}{
-		{pkgbits.SectionString, "SectionString"},
-		{pkgbits.SectionMeta, "SectionMeta"},
-		{pkgbits.SectionPosBase, "SectionPosBase"},
-		{pkgbits.SectionPkg, "SectionPkg"},
-		{pkgbits.SectionName, "SectionName"},
-		{pkgbits.SectionType, "SectionType"},
-		{pkgbits.SectionObj, "SectionObj"},
-		{pkgbits.SectionObjExt, "SectionObjExt"},
-		{pkgbits.SectionObjDict, "SectionObjDict"},
-		{pkgbits.SectionBody, "SectionBody"},
+// readExportFile reads an archive, object file or raw Unified IR file
+// and returns its export data.
+func readExportFile(in input) (*exportFile, error) {
+	data, err := os.ReadFile(in.filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
 	}
-
-	for _, sec := range sections {
-		count := decoder.NumElems(sec.kind)
-		fmt.Printf("  %-16s: %4d elements\n", sec.name, count)
+	if data, err = importer.ExpandThinArchive(data, filepath.Dir(in.filename)); err != nil {
+		return nil, fmt.Errorf("reading thin archive: %w", err)
 	}
-	fmt.Println()
-
-	// Show string table
-	fmt.Println("=== SectionString (Deduplicated Strings) ===")
-	stringCount := decoder.NumElems(pkgbits.SectionString)
-	if stringCount > 0 {
-		fmt.Printf("Total strings: %d\n", stringCount)
-		maxShow := stringCount
-		if limit > 0 && limit < stringCount {
-			maxShow = limit
-			fmt.Printf("(showing first %d)\n", maxShow)
-		}
-		fmt.Println()
 
-		for i := 0; i < maxShow; i++ {
-			str := decoder.StringIdx(pkgbits.Index(i))
-			if len(str) > 80 {
-				str = str[:77] + "..."
-			}
-			// Escape special characters
-			str = strings.ReplaceAll(str, "\n", "\\n")
-			str = strings.ReplaceAll(str, "\t", "\\t")
-			fmt.Printf("  [%3d] %q\n", i, str)
-		}
-		if maxShow < stringCount {
-			fmt.Printf("  ... and %d more\n", stringCount-maxShow)
-		}
-	} else {
-		fmt.Println("  (empty)")
+	f := &exportFile{input: in}
+	f.uirData, f.container, err = importer.ExtractExportData(data)
+	if err != nil {
+		return nil, fmt.Errorf("extracting Unified IR from %v: %w", f.container, err)
 	}
-	fmt.Println()
-
-	// Show position bases (source files)
-	fmt.Println("=== SectionPosBase (Source File Locations) ===")
-	posBaseCount := decoder.NumElems(pkgbits.SectionPosBase)
-	if posBaseCount > 0 {
-		maxShow := posBaseCount
-		if limit > 0 && limit < posBaseCount {
-			maxShow = limit
+	if f.container != importer.RawContainer {
+		if f.header, err = importer.ExtractObjectHeader(data); err != nil {
+			return nil, fmt.Errorf("parsing object header: %w", err)
 		}
-		for i := 0; i < maxShow; i++ {
-			r := decoder.NewDecoder(pkgbits.SectionPosBase, pkgbits.Index(i), pkgbits.SyncPosBase)
-			filename := r.String()
-			isFileBase := r.Bool()
-			if isFileBase {
-				fmt.Printf("  [%d] %s (file base)\n", i, filename)
-			} else {
-				// Line base
-				fmt.Printf("  [%d] %s (line base)\n", i, filename)
-			}
-		}
-		if maxShow < posBaseCount {
-			fmt.Printf("  ... and %d more\n", posBaseCount-maxShow)
-		}
-	} else {
-		fmt.Println("  (none)")
 	}
-	fmt.Println()
-
-	// Show package table
-	fmt.Println("=== SectionPkg (Package References) ===")
-	pkgCount := decoder.NumElems(pkgbits.SectionPkg)
-	if pkgCount > 0 {
-		maxShow := pkgCount
-		if limit > 0 && limit < pkgCount {
-			maxShow = limit
-		}
-		for i := 0; i < maxShow; i++ {
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						fmt.Printf("  [%d] (error reading package: %v)\n", i, r)
-					}
-				}()
-				r := decoder.NewDecoder(pkgbits.SectionPkg, pkgbits.Index(i), pkgbits.SyncPkgDef)
-				r.Sync(pkgbits.SyncPkg)
-				path := r.String()
-				name := r.String()
-				if path == "" {
-					fmt.Printf("  [%d] <self> (name: %s)\n", i, name)
-				} else {
-					fmt.Printf("  [%d] %s (name: %s)\n", i, path, name)
-				}
-			}()
-		}
-		if maxShow < pkgCount {
-			fmt.Printf("  ... and %d more\n", pkgCount-maxShow)
-		}
-	} else {
-		fmt.Println("  (none)")
+	if f.container == importer.ArchiveContainer {
+		_, f.archiveErr = importer.ArchiveMembers(data)
 	}
-	fmt.Println()
-
-	// Show type table summary
-	fmt.Println("=== SectionType (Type Definitions) ===")
-	typeCount := decoder.NumElems(pkgbits.SectionType)
-	fmt.Printf("Total types: %d\n", typeCount)
-	fmt.Println()
+	return f, nil
+}
 
-	// Show object table
-	fmt.Println("=== SectionObj (Object Declarations) ===")
-	objCount := decoder.NumElems(pkgbits.SectionObj)
-	if objCount > 0 {
-		objCounts := make(map[string]int)
-		for i := 0; i < objCount; i++ {
-			_, _, tag := decoder.PeekObj(pkgbits.Index(i))
-			objCounts[objTagName(tag)]++
-		}
-		fmt.Printf("Total objects: %d\n", objCount)
-		for name, count := range objCounts {
-			fmt.Printf("  %-10s: %d\n", name, count)
-		}
-	} else {
-		fmt.Println("  (none)")
+// newPkgReader returns a pkgReader for the file's export data,
+// configured for the build it records.
+func (f *exportFile) newPkgReader(decoder *pkgbits.PkgDecoder, cfg *importCfg) *pkgReader {
+	pr := newPkgReader(*decoder)
+	if f.header != nil {
+		pr.goarch = f.header.GOARCH
+		pr.newInliner = slices.Contains(f.header.Experiments, "newinliner")
 	}
-	fmt.Println()
+	pr.selfPath, pr.selfPathSource = f.selfPath(decoder, cfg)
+	return pr
+}
 
-	// Show private root (function bodies)
-	fmt.Println("=== SectionMeta - Private Root (Function Bodies & Internal Data) ===")
-	r := decoder.NewDecoder(pkgbits.SectionMeta, pkgbits.PrivateRootIdx, pkgbits.SyncPrivate)
-	hasInittask := r.Bool()
-	fmt.Printf("Has .inittask: %v\n", hasInittask)
+// newDecoder returns a decoder for the file's export data, given the
+// import path that selfPath finds so that its errors name the package.
+func (f *exportFile) newDecoder(cfg *importCfg) (*pkgbits.PkgDecoder, error) {
+	input := string(f.uirData[1:]) // skip the 'u' prefix
+	decoder, err := pkgbits.ParsePkgDecoder("", input)
+	if err != nil {
+		// Without the public root, the path may still be known
+		// from elsewhere.
+		decoder = nil
+	}
+	if path, _ := f.selfPath(decoder, cfg); path != "" {
+		return pkgbits.ParsePkgDecoder(path, input)
+	}
+	return decoder, err
+}
 
-	bodyCount := r.Len()
-	fmt.Printf("Function bodies: %d\n", bodyCount)
-	if bodyCount > 0 {
-		fmt.Println()
-		maxShow := bodyCount
-		if limit > 0 && limit < bodyCount {
-			maxShow = limit
+// publicRootPath returns the package path that the public root
+// records, or "" if it is omitted or cannot be read.
+func publicRootPath(decoder *pkgbits.PkgDecoder) (path string) {
+	defer func() {
+		if recover() != nil {
+			path = ""
 		}
-		for i := 0; i < bodyCount; i++ {
-			pkgPath := r.String()
-			symName := r.String()
-			bodyIdx := r.Reloc(pkgbits.SectionBody)
-			if i < maxShow {
-				fmt.Printf("  [%d] %s.%s (body index: %d)\n", i, pkgPath, symName, bodyIdx)
-			}
-		}
-		if maxShow < bodyCount {
-			fmt.Printf("  ... and %d more\n", bodyCount-maxShow)
+	}()
+	r := decoder.NewDecoder(pkgbits.SectionMeta, pkgbits.PublicRootIdx, pkgbits.SyncPublic)
+	r.Sync(pkgbits.SyncPkg)
+	pkg := decoder.NewDecoder(pkgbits.SectionPkg, r.Reloc(pkgbits.SectionPkg), pkgbits.SyncPkgDef)
+	return pkg.String()
+}
+
+// selfPath returns the import path of the package described by the
+// file, and where it was found. The export data of older toolchains
+// omits it, so it is taken from the first of these that has it: the
+// public root, the object header (which only identifies package main),
+// cfg, or the go list entry that the file was found by. decoder is nil
+// if the export data cannot be parsed.
+func (f *exportFile) selfPath(decoder *pkgbits.PkgDecoder, cfg *importCfg) (path, source string) {
+	if decoder != nil {
+		if path := publicRootPath(decoder); path != "" {
+			return path, "public root"
 		}
 	}
-	r.Sync(pkgbits.SyncEOF)
+	if f.header != nil && f.header.Main {
+		return "main", "object header"
+	}
+	if f.pathSource == "importcfg" {
+		return f.importPath, "importcfg"
+	}
+	if path := cfg.pathOf(f.filename); path != "" {
+		return path, "importcfg"
+	}
+	if f.pathSource == "go list" {
+		return f.importPath, "go list"
+	}
+	return "", ""
+}
 
-	return nil
+// showDetailedFormat shows detailed binary format information, in the
+// given output format. cfg, if non-nil, locates the archives of
+// referenced packages.
+func showDetailedFormat(f *exportFile, cfg *importCfg, format string, limit int) error {
+	rep, err := newReport(f, cfg, limit)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "json":
+		return rep.writeJSON(os.Stdout)
+	case "html":
+		return rep.writeHTML(os.Stdout)
+	default:
+		rep.writeText(os.Stdout)
+		return nil
+	}
 }
 
 // typeCodeName returns a human-readable name for a type code
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"go/constant"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/importer"
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// testSource is the package that examplePackage writes the export data
+// of.
+const testSource = `package example
+
+import "fmt"
+
+type Number interface{ ~int | ~float64 }
+
+type List[T any] struct {
+	next *List[T]
+	val  T
+}
+
+func Sum[T Number](xs ...T) (s T) {
+	for _, x := range xs {
+		s += x
+	}
+	return
+}
+
+const Greeting = "hello\tworld"
+
+type Person struct{ Name string }
+
+func (p *Person) String() string { return "hi " + p.Name }
+
+func Describe(p *Person) string { return fmt.Sprint(p, Sum(1, 2)) }
+
+func (l *List[E]) Push(v E) *List[E] { return &List[E]{next: l, val: v} }
+`
+
+const testPath = "example.com/example"
+
+// examplePackage returns the export data of testSource, with sync
+// markers, as the compiler writes it for the package at testPath.
+func examplePackage() []byte {
+	w := uirtest.New(pkgbits.V2, 0, testPath, "example")
+	fmtPkg := w.Pkg("fmt", "fmt")
+	w.Import(fmtPkg)
+	file := w.File("example.go")
+	at := func(line uint) uirtest.Pos { return file.Pos(line, 6) }
+
+	number := w.NewObject(w.Self, "Number", pkgbits.ObjType)
+	list := w.NewObject(w.Self, "List", pkgbits.ObjType)
+	sum := w.NewObject(w.Self, "Sum", pkgbits.ObjFunc)
+	greeting := w.NewObject(w.Self, "Greeting", pkgbits.ObjConst)
+	person := w.NewObject(w.Self, "Person", pkgbits.ObjType)
+	describe := w.NewObject(w.Self, "Describe", pkgbits.ObjFunc)
+	sprint := w.NewObject(fmtPkg, "Sprint", pkgbits.ObjFunc)
+
+	intType := w.Basic(types.Int)
+	stringType := w.Basic(types.String)
+	anyObj := w.NewObject(w.Pkg("builtin", ""), "any", pkgbits.ObjStub)
+	anyType := w.Named(anyObj.Ref())
+
+	number.Pos = at(5)
+	number.Type = w.Interface(nil, []uirtest.Type{w.Union(
+		uirtest.Term{Tilde: true, Type: intType},
+		uirtest.Term{Tilde: true, Type: w.Basic(types.Float64)},
+	)}, false)
+
+	list.Pos = at(7)
+	list.TypeParams = []uirtest.TypeParam{{Pos: file.Pos(7, 11), Name: "T", Bound: anyType, Basic: true}}
+	t := list.Derive(w.TypeParam(0))
+	listT := list.Derive(w.Pointer(list.Derive(w.Named(list.Ref(t)))))
+	list.Type = list.Derive(w.Struct(
+		uirtest.Field{Pos: file.Pos(8, 2), Name: "next", Type: listT},
+		uirtest.Field{Pos: file.Pos(9, 2), Name: "val", Type: t},
+	))
+
+	// Push has its own receiver type parameter, and so its own derived
+	// types.
+	e := list.Derive(w.TypeParam(0))
+	listE := list.Derive(w.Pointer(list.Derive(w.Named(list.Ref(e)))))
+	push := &uirtest.Method{
+		Pos:            file.Pos(27, 19),
+		Name:           "Push",
+		RecvTypeParams: []string{"E"},
+		Recv:           uirtest.Param{Pos: file.Pos(27, 7), Name: "l", Type: listE},
+		Sig: &uirtest.Signature{
+			Params:  []uirtest.Param{{Pos: file.Pos(27, 24), Name: "v", Type: e}},
+			Results: []uirtest.Param{{Pos: file.Pos(27, 28), Type: listE}},
+		},
+	}
+	list.Methods = []*uirtest.Method{push}
+
+	sum.Pos = at(12)
+	sum.TypeParams = []uirtest.TypeParam{{Pos: file.Pos(12, 10), Name: "T", Bound: w.Named(number.Ref())}}
+	t = sum.Derive(w.TypeParam(0))
+	sum.Sig = &uirtest.Signature{
+		Params:   []uirtest.Param{{Pos: file.Pos(12, 20), Name: "xs", Type: sum.Derive(w.Slice(t))}},
+		Results:  []uirtest.Param{{Pos: file.Pos(12, 31), Name: "s", Type: t}},
+		Variadic: true,
+	}
+	sum.RTypes = []uirtest.Type{t}
+	sum.FuncExt = &uirtest.FuncExt{Body: ptr(sumBody(w, t))}
+
+	greeting.Pos = at(19)
+	greeting.Type = stringType
+	greeting.Value = constant.MakeString("hello\tworld")
+
+	person.Pos = at(21)
+	person.Type = w.Struct(uirtest.Field{Pos: file.Pos(21, 20), Name: "Name", Type: stringType})
+	personPtr := w.Pointer(w.Named(person.Ref()))
+	str := &uirtest.Method{
+		Pos:  file.Pos(23, 18),
+		Name: "String",
+		Recv: uirtest.Param{Pos: file.Pos(23, 7), Name: "p", Type: personPtr},
+		Sig:  &uirtest.Signature{Results: []uirtest.Param{{Pos: file.Pos(23, 27), Type: stringType}}},
+		Ext: &uirtest.FuncExt{
+			ABI:     1,
+			Escapes: []string{"esc:\x00\x00\x00\x01"},
+			Inline:  &uirtest.Inline{Cost: 7, CanDelayResults: true},
+		},
+	}
+	person.Methods = []*uirtest.Method{str}
+	person.TypeExt = uirtest.TypeExt{TypeSym: 3, PtrSym: 4}
+	w.InlineBody(testPath, "(*Person).String", stringBody(w, stringType))
+
+	describe.Pos = at(25)
+	describe.Sig = &uirtest.Signature{
+		Params:  []uirtest.Param{{Pos: file.Pos(25, 15), Name: "p", Type: personPtr}},
+		Results: []uirtest.Param{{Pos: file.Pos(25, 26), Type: stringType}},
+	}
+	describe.FuncExt = &uirtest.FuncExt{ABI: 1, Escapes: []string{""}}
+	w.InlineBody(testPath, "Describe", describeBody(w, sprint, sum, intType))
+	push.Ext = &uirtest.FuncExt{Body: ptr(pushBody(w, listE))}
+
+	sprint.Sig = &uirtest.Signature{
+		Params:   []uirtest.Param{{Name: "a", Type: w.Slice(anyType)}},
+		Results:  []uirtest.Param{{Type: stringType}},
+		Variadic: true,
+	}
+	sprint.FuncExt = &uirtest.FuncExt{ABI: 1, Escapes: []string{"esc:\x01"}}
+
+	for _, obj := range []*uirtest.Object{number, list, sum, greeting, person, describe} {
+		w.Export(obj)
+	}
+	for _, obj := range []*uirtest.Object{number, list, sum, greeting, person, describe, sprint, anyObj} {
+		obj.Flush()
+	}
+	return w.Bytes()
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// rawFile returns an exportFile for data, which is raw export data.
+func rawFile(data []byte) *exportFile {
+	return &exportFile{container: importer.RawContainer, uirData: data}
+}
+
+// testReader returns a pkgReader for data, which is raw export data.
+func testReader(t *testing.T, data []byte) *pkgReader {
+	t.Helper()
+	f := rawFile(data)
+	decoder, err := f.newDecoder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.newPkgReader(decoder, nil)
+}
+
+// exampleObj returns the object of testReader(examplePackage()) with
+// the given name.
+func exampleObj(t *testing.T, pr *pkgReader, name string) (pkgbits.Index, *objNode) {
+	t.Helper()
+	for i := range pr.NumElems(pkgbits.SectionObj) {
+		idx := pkgbits.Index(i)
+		if obj := pr.objIdx(idx); obj.name == name {
+			return idx, obj
+		}
+	}
+	t.Fatalf("no object %s", name)
+	return 0, nil
+}
+
+// writeTemp writes data to a file named name in a temporary directory
+// and returns its path.
+func writeTemp(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, data, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestReadExportFile(t *testing.T) {
+	data := examplePackage()
+	tests := []struct {
+		name      string
+		data      []byte
+		container importer.Container
+	}{
+		{"example.a", uirtest.Archive(uirtest.Header, data, "obj"), importer.ArchiveContainer},
+		{"example.o", uirtest.ObjectFile(uirtest.Header, data, "obj"), importer.ObjectContainer},
+		{"example.uir", data, importer.RawContainer},
+	}
+	for _, test := range tests {
+		f, err := readExportFile(input{filename: writeTemp(t, test.name, test.data)})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if f.container != test.container {
+			t.Errorf("%s: read as %v, want %v", test.name, f.container, test.container)
+		}
+		if !bytes.Equal(f.uirData, data) {
+			t.Errorf("%s: export data differs from what was written", test.name)
+		}
+		if f.archiveErr != nil {
+			t.Errorf("%s: archive has defect %v", test.name, f.archiveErr)
+		}
+		if hasHeader := test.container != importer.RawContainer; (f.header != nil) != hasHeader {
+			t.Errorf("%s: header is %+v", test.name, f.header)
+		} else if hasHeader && f.header.GOARCH != "amd64" {
+			t.Errorf("%s: header has GOARCH %q, want amd64", test.name, f.header.GOARCH)
+		}
+	}
+}
+
+func TestTruncatedObject(t *testing.T) {
+	data := uirtest.Archive(uirtest.Header, examplePackage(), strings.Repeat("obj", 100))
+	i := bytes.Index(data, []byte("_go_.o"))
+
+	// Cutting the archive inside _go_.o leaves the export data intact.
+	cut, err := readExportFile(input{filename: writeTemp(t, "cut.a", data[:i+100])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cut.archiveErr == nil || !strings.Contains(cut.archiveErr.Error(), "truncated") {
+		t.Errorf("archive cut inside _go_.o has defect %v, want truncation", cut.archiveErr)
+	}
+}
+
+func TestDecoderPkgPath(t *testing.T) {
+	// Export data written without the package path in its public root,
+	// as older toolchains do, found by go list.
+	w := uirtest.New(pkgbits.V1, -1, "", "example")
+	f := rawFile(w.Bytes())
+	f.input = input{filename: "example.a", importPath: testPath, pathSource: "go list"}
+
+	decoder, err := f.newDecoder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoder.PkgPath(); got != testPath {
+		t.Errorf("decoder has package path %q, want %q", got, testPath)
+	}
+
+	// A truncated header hides the public root, but the path is still
+	// known from go list.
+	f.uirData = f.uirData[:20]
+	if _, err := f.newDecoder(nil); err == nil || !strings.Contains(err.Error(), testPath) {
+		t.Errorf("truncated export data gives error %v, want mention of %q", err, testPath)
+	}
+}
+
+func TestSelfPath(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "example.a")
+	cfg := &importCfg{packageFile: map[string]string{"example.com/other": filepath.Join(dir, "other.a"), testPath: filename}}
+
+	withPath, err := rawFile(examplePackage()).newDecoder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutPath, err := rawFile(uirtest.New(pkgbits.V1, -1, "", "example").Bytes()).newDecoder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainHeader := &importer.ObjectHeader{GOOS: "linux", GOARCH: "amd64", Main: true}
+
+	tests := []struct {
+		name       string
+		f          exportFile
+		decoder    *pkgbits.PkgDecoder
+		cfg        *importCfg
+		path, from string
+	}{
+		{"public root", exportFile{input: input{filename: filename}, header: mainHeader}, withPath, cfg, testPath, "public root"},
+		{"main", exportFile{input: input{filename: filename}, header: mainHeader}, withoutPath, cfg, "main", "object header"},
+		{"importcfg lookup", exportFile{input: input{filename: "x.a", importPath: "a/b", pathSource: "importcfg"}}, withoutPath, cfg, "a/b", "importcfg"},
+		{"importcfg file", exportFile{input: input{filename: filename, importPath: "a/b", pathSource: "go list"}}, withoutPath, cfg, testPath, "importcfg"},
+		{"go list", exportFile{input: input{filename: filename, importPath: "a/b", pathSource: "go list"}}, nil, nil, "a/b", "go list"},
+		{"unknown", exportFile{input: input{filename: filename}}, withoutPath, nil, "", ""},
+	}
+	for _, test := range tests {
+		path, from := test.f.selfPath(test.decoder, test.cfg)
+		if path != test.path || from != test.from {
+			t.Errorf("%s: path is %q from %q, want %q from %q", test.name, path, from, test.path, test.from)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jespino/unified-ir-reader/importer"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// runMembers lists the members of an archive and decodes the linker
+// object header of each Go object member, checking that its
+// fingerprint matches the export data's.
+func runMembers(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+	if c := importer.DetectContainer(data); c != importer.ArchiveContainer {
+		return fmt.Errorf("%s is not an ar archive (found %v)", filename, c)
+	}
+
+	// Members are listed even if the archive is damaged further on.
+	members, membersErr := importer.ArchiveMembers(data)
+	if err := importer.LoadThinMembers(members, filepath.Dir(filename)); err != nil {
+		return err
+	}
+
+	var fingerprint *[8]byte
+	for _, m := range members {
+		if m.Name != "__.PKGDEF" {
+			continue
+		}
+		if uirData, err := importer.ExtractUnifiedIR(m.Data); err == nil {
+			if decoder, err := pkgbits.ParsePkgDecoder("", string(uirData[1:])); err == nil {
+				fp := decoder.Fingerprint()
+				fingerprint = &fp
+			}
+		}
+	}
+
+	fmt.Println("=== Archive Members ===")
+	for i, m := range members {
+		kind := importer.MemberKind(m.Data)
+		switch {
+		case m.NestedOffset != 0:
+			kind += fmt.Sprintf(" (external, in %s at offset %d)", m.File, m.NestedOffset)
+		case m.External:
+			kind += " (external)"
+		}
+		fmt.Printf("  %-5s %-24s %8d bytes at offset %-8d %s\n", fmt.Sprintf("[%d]", i), m.Name, m.Size, m.Offset, kind)
+	}
+
+	for _, m := range members {
+		if importer.MemberKind(m.Data) != "Go object" {
+			continue
+		}
+		fmt.Println()
+		fmt.Printf("=== Linker Object: %s ===\n", m.Name)
+		h, err := importer.ParseGoObjHeader(m.Data)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		fmt.Printf("Magic: %q\n", h.Magic)
+		switch {
+		case h.Flags&importer.GoObjFlagFromAssembly != 0:
+			// The assembler doesn't see the export data.
+			fmt.Printf("Fingerprint: %x (assembled, not checked)\n", h.Fingerprint)
+		case fingerprint == nil:
+			fmt.Printf("Fingerprint: %x (no export data to compare)\n", h.Fingerprint)
+		case h.Fingerprint == *fingerprint:
+			fmt.Printf("Fingerprint: %x (matches export data)\n", h.Fingerprint)
+		default:
+			fmt.Printf("Fingerprint: %x (MISMATCH: export data has %x)\n", h.Fingerprint, *fingerprint)
+		}
+		fmt.Printf("Flags: %#x", h.Flags)
+		if s := h.FlagString(); s != "" {
+			fmt.Printf(" (%s)", s)
+		}
+		fmt.Println()
+		fmt.Printf("Symbols: %d package, %d hashed64, %d hashed, %d non-package, %d referenced\n",
+			h.NSyms, h.NHashed64Defs, h.NHashedDefs, h.NNonPkgDefs, h.NNonPkgRefs)
+		fmt.Printf("Relocations: %d\n", h.NRelocs)
+	}
+
+	return membersErr
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"go/constant"
+	"strings"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// An objNode is a decoded SectionObj element, together with its name
+// and dictionary. Which fields are set depends on tag.
+type objNode struct {
+	pkg  pkgbits.Index
+	name string
+	tag  pkgbits.CodeObj
+	dict *readerDict
+	pos  srcPos
+
+	typ     typeInfo       // ObjAlias, ObjConst, ObjVar; underlying type for ObjType
+	val     constant.Value // ObjConst
+	sig     *signature     // ObjFunc
+	methods []*methodNode  // ObjType
+}
+
+// A methodNode is a method declared on a defined type.
+type methodNode struct {
+	pos      srcPos
+	name     string
+	rtparams []string // receiver type parameter names
+	recv     param
+	sig      *signature
+}
+
+// objIdx returns the specified object, reading it first if needed.
+func (pr *pkgReader) objIdx(idx pkgbits.Index) *objNode {
+	if obj := pr.objs[idx]; obj != nil {
+		return obj
+	}
+
+	rname := pr.newReader(pkgbits.SectionName, idx, pkgbits.SyncObject1)
+	pkg, name := rname.qualifiedIdent()
+	tag := pkgbits.CodeObj(rname.Code(pkgbits.SyncCodeObj))
+
+	obj := &objNode{pkg: pkg, name: name, tag: tag}
+	if tag == pkgbits.ObjStub {
+		pr.objs[idx] = obj
+		return obj
+	}
+
+	obj.dict = pr.objDictIdx(idx)
+
+	r := pr.newReader(pkgbits.SectionObj, idx, pkgbits.SyncObject1)
+	r.dict = obj.dict
+
+	switch tag {
+	default:
+		panic(fmt.Errorf("unexpected object tag: %v", tag))
+
+	case pkgbits.ObjAlias:
+		obj.pos = r.pos()
+		if r.Version().Has(pkgbits.AliasTypeParamNames) {
+			r.typeParamNames()
+		}
+		obj.typ = r.typInfo()
+
+	case pkgbits.ObjConst:
+		obj.pos = r.pos()
+		obj.typ = r.typInfo()
+		obj.val = r.Value()
+
+	case pkgbits.ObjFunc:
+		obj.pos = r.pos()
+		r.typeParamNames()
+		obj.sig = r.signature()
+		r.pos()
+
+	case pkgbits.ObjType:
+		obj.pos = r.pos()
+		r.typeParamNames()
+		obj.typ = r.typInfo()
+		obj.methods = make([]*methodNode, r.Len())
+		for i := range obj.methods {
+			obj.methods[i] = r.method()
+		}
+
+	case pkgbits.ObjVar:
+		obj.pos = r.pos()
+		obj.typ = r.typInfo()
+	}
+
+	pr.objs[idx] = obj
+	return obj
+}
+
+// typeParamNames reads the names of the object's explicit type
+// parameters and records them in the current dictionary.
+func (r *reader) typeParamNames() {
+	copy(r.dict.tparams[r.dict.implicits:], r.typeParamNameList())
+}
+
+// typeParamNameList reads a list of explicit type parameter names, one
+// for each bound of the current dictionary. Methods list their own
+// names for the receiver type's parameters.
+func (r *reader) typeParamNameList() []string {
+	r.Sync(pkgbits.SyncTypeParamNames)
+
+	names := make([]string, len(r.dict.bounds))
+	for i := range names {
+		r.pos()
+		_, names[i] = r.localIdent()
+	}
+	return names
+}
+
+func (r *reader) method() *methodNode {
+	r.Sync(pkgbits.SyncMethod)
+	pos := r.pos()
+	_, name := r.selector()
+	m := &methodNode{pos: pos, name: name}
+	m.rtparams = r.typeParamNameList()
+	m.recv = r.param()
+	m.sig = r.signature()
+	r.pos()
+	return m
+}
+
+// @@@ Formatting
+
+// declString returns the Go declaration of obj, without its methods.
+// Objects copied from other packages have qualified names.
+func (pr *pkgReader) declString(obj *objNode) string {
+	var buf strings.Builder
+	name := pr.qualifier(obj.pkg) + obj.name
+
+	switch obj.tag {
+	case pkgbits.ObjAlias:
+		buf.WriteString("type ")
+		buf.WriteString(name)
+		pr.writeTypeParams(&buf, obj.dict)
+		buf.WriteString(" = ")
+		pr.writeType(&buf, obj.typ, obj.dict)
+
+	case pkgbits.ObjConst:
+		fmt.Fprintf(&buf, "const %s ", name)
+		pr.writeType(&buf, obj.typ, obj.dict)
+		buf.WriteString(" = ")
+		buf.WriteString(valueString(obj.val))
+
+	case pkgbits.ObjFunc:
+		buf.WriteString("func ")
+		buf.WriteString(name)
+		pr.writeTypeParams(&buf, obj.dict)
+		pr.writeSignature(&buf, obj.sig, obj.dict)
+
+	case pkgbits.ObjType:
+		buf.WriteString("type ")
+		buf.WriteString(name)
+		pr.writeTypeParams(&buf, obj.dict)
+		buf.WriteByte(' ')
+		pr.writeType(&buf, obj.typ, obj.dict)
+
+	case pkgbits.ObjVar:
+		fmt.Fprintf(&buf, "var %s ", name)
+		pr.writeType(&buf, obj.typ, obj.dict)
+
+	case pkgbits.ObjStub:
+		if pr.pkgIdx(obj.pkg).path == "builtin" {
+			fmt.Fprintf(&buf, "%s (predeclared)", name)
+		} else {
+			fmt.Fprintf(&buf, "%s (stub)", name)
+		}
+	}
+
+	return buf.String()
+}
+
+// methodDict returns the dictionary for method m of obj: the type's
+// dictionary, with the type parameters named as in m's receiver, which
+// may rename them.
+func methodDict(obj *objNode, m *methodNode) *readerDict {
+	dict := *obj.dict
+	dict.tparams = append(dict.tparams[:dict.implicits:dict.implicits], m.rtparams...)
+	return &dict
+}
+
+// methodString returns the Go declaration of method m of obj.
+func (pr *pkgReader) methodString(obj *objNode, m *methodNode) string {
+	dict := methodDict(obj, m)
+
+	var buf strings.Builder
+	buf.WriteString("func (")
+	if m.recv.name != "" {
+		buf.WriteString(m.recv.name)
+		buf.WriteByte(' ')
+	}
+	pr.writeType(&buf, m.recv.typ, dict)
+	buf.WriteString(") ")
+	buf.WriteString(m.name)
+	pr.writeSignature(&buf, m.sig, dict)
+	return buf.String()
+}
+
+// writeTypeParams writes the explicit type parameter list of dict,
+// if any.
+func (pr *pkgReader) writeTypeParams(buf *strings.Builder, dict *readerDict) {
+	if len(dict.bounds) == 0 {
+		return
+	}
+	buf.WriteByte('[')
+	for i, bound := range dict.bounds {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(dict.tparams[dict.implicits+i])
+		buf.WriteByte(' ')
+		pr.writeType(buf, bound, dict)
+	}
+	buf.WriteByte(']')
+}
+
+// valueString returns the Go syntax for a constant value.
+func valueString(val constant.Value) string {
+	switch val.Kind() {
+	case constant.Float, constant.Complex:
+		// ExactString would print floats as fractions.
+		return val.String()
+	}
+	return val.ExactString()
+}
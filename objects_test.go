@@ -0,0 +1,91 @@
+package main
+
+import (
+	"go/constant"
+	"go/types"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+func TestDeclString(t *testing.T) {
+	pr := testReader(t, examplePackage())
+	for name, want := range map[string]string{
+		"Number":   "type Number interface{~int | ~float64}",
+		"List":     "type List[T any] struct{next *List[T]; val T}",
+		"Sum":      "func Sum[T Number](xs ...T) (s T)",
+		"Greeting": `const Greeting string = "hello\tworld"`,
+		"Person":   "type Person struct{Name string}",
+		"Describe": "func Describe(p *Person) string",
+	} {
+		_, obj := exampleObj(t, pr, name)
+		if got := pr.declString(obj); got != want {
+			t.Errorf("%s is declared as %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestMethodTypeParamNames(t *testing.T) {
+	pr := testReader(t, examplePackage())
+	_, list := exampleObj(t, pr, "List")
+
+	// Push renames the type parameter of List, which must only affect
+	// the method.
+	const wantType = "type List[T any] struct{next *List[T]; val T}"
+	const wantMethod = "func (l *List[E]) Push(v E) *List[E]"
+	if len(list.methods) != 1 {
+		t.Fatalf("List has %d methods, want 1", len(list.methods))
+	}
+	if got := pr.methodString(list, list.methods[0]); got != wantMethod {
+		t.Errorf("Push is declared as %q, want %q", got, wantMethod)
+	}
+	if got := pr.declString(list); got != wantType {
+		t.Errorf("List is declared as %q, want %q", got, wantType)
+	}
+}
+
+func TestObjectTags(t *testing.T) {
+	w := uirtest.New(pkgbits.V2, 0, testPath, "example")
+	file := w.File("objects.go")
+	intType := w.Basic(types.Int)
+
+	alias := w.NewObject(w.Self, "Pair", pkgbits.ObjAlias)
+	alias.Pos = file.Pos(1, 6)
+	alias.TypeParams = []uirtest.TypeParam{{Name: "K", Bound: w.Interface(nil, nil, false)}}
+	k := alias.Derive(w.TypeParam(0))
+	alias.Type = alias.Derive(w.Map(k, k))
+
+	v := w.NewObject(w.Self, "count", pkgbits.ObjVar)
+	v.Pos = file.Pos(2, 5)
+	v.Type = intType
+	v.Link = uirtest.Link{SymIdx: -1, Linkname: "runtime.count"}
+
+	c := w.NewObject(w.Self, "Pi", pkgbits.ObjConst)
+	c.Pos = file.Pos(3, 7)
+	c.Type = w.Basic(types.UntypedFloat)
+	c.Value = constant.MakeFloat64(3.25)
+
+	stub := w.NewObject(w.Pkg("builtin", ""), "len", pkgbits.ObjStub)
+
+	objs := []*uirtest.Object{alias, v, c, stub}
+	for _, obj := range objs {
+		w.Export(obj)
+		obj.Flush()
+	}
+
+	pr := testReader(t, w.Bytes())
+	for i, want := range []string{
+		"type Pair[K any] = map[K]K",
+		"var count int",
+		"const Pi untyped float = 3.25",
+		"len (predeclared)",
+	} {
+		if got := pr.declString(pr.objIdx(objs[i].Idx)); got != want {
+			t.Errorf("object %d is declared as %s, want %s", i, got, want)
+		}
+	}
+	if got, want := pr.objIdx(v.Idx).pos.String(), "objects.go:2:5"; got != want {
+		t.Errorf("count is at %s, want %s", got, want)
+	}
+}
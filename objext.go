@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// An objExt is a decoded SectionObjExt element: the compiler-private
+// extension data of an object. Which fields are set depends on the
+// object's tag.
+type objExt struct {
+	funcs []*funcExt // ObjFunc (one), ObjType (one per method)
+
+	// ObjType.
+	pragma  pragmaFlag
+	typeSym int64 // symbol index of the type descriptor for T, or -1
+	ptrSym  int64 // symbol index of the type descriptor for *T, or -1
+
+	// ObjVar.
+	link linkInfo
+}
+
+// A funcExt is the extension data of a function or method.
+type funcExt struct {
+	pragma pragmaFlag
+	link   linkInfo
+
+	// Only present when the export data was built for GOARCH=wasm.
+	wasmImport [2]string // module, name
+	wasmExport string
+
+	// extended reports whether the compiler recorded its own analysis
+	// results. If not, body refers to the function's SectionBody
+	// element instead; this is the case for generic functions, which
+	// are compiled by importers.
+	extended bool
+	body     pkgbits.Index
+
+	abi     uint64
+	escapes []string // escape analysis notes for the receiver and parameters
+	inl     *inlineInfo
+}
+
+// An inlineInfo describes an inlinable function.
+type inlineInfo struct {
+	cost            int
+	canDelayResults bool
+	properties      string // only with GOEXPERIMENT=newinliner
+}
+
+// A linkInfo describes how an object's linker symbol is found: by its
+// index in the object file, or by a //go:linkname directive.
+type linkInfo struct {
+	symIdx   int64 // -1 if not indexed
+	linkname string
+	std      bool // linkname is pushed by the standard library
+}
+
+// A pragmaFlag is a set of //go: directives, as in cmd/compile's
+// ir.PragmaFlag.
+type pragmaFlag int
+
+var pragmaNames = []string{
+	"nointerface",
+	"noescape",
+	"norace",
+	"nosplit",
+	"noinline",
+	"nocheckptr",
+	"cgo_unsafe_args",
+	"uintptrkeepalive",
+	"uintptrescapes",
+	"systemstack",
+	"nowritebarrier",
+	"nowritebarrierrec",
+	"yeswritebarrierrec",
+	"build",
+	"registerparams",
+}
+
+func (f pragmaFlag) String() string {
+	return strings.Join(f.names(), " ")
+}
+
+// names returns the directives in f, such as "//go:noinline".
+func (f pragmaFlag) names() []string {
+	var names []string
+	for i, name := range pragmaNames {
+		if f&(1<<i) != 0 {
+			names = append(names, "//go:"+name)
+		}
+	}
+	if rest := f &^ (1<<len(pragmaNames) - 1); rest != 0 {
+		names = append(names, fmt.Sprintf("%#x", int(rest)))
+	}
+	return names
+}
+
+// objExtIdx returns the extension data of the specified object, which
+// must already be decoded as obj.
+func (pr *pkgReader) objExtIdx(idx pkgbits.Index, obj *objNode) *objExt {
+	r := pr.newReader(pkgbits.SectionObjExt, idx, pkgbits.SyncObject1)
+	r.dict = obj.dict
+
+	ext := new(objExt)
+	switch obj.tag {
+	case pkgbits.ObjFunc:
+		ext.funcs = []*funcExt{r.funcExt(len(obj.sig.params))}
+
+	case pkgbits.ObjType:
+		r.Sync(pkgbits.SyncTypeExt)
+		ext.pragma = r.pragmaFlag()
+		ext.typeSym = r.Int64()
+		ext.ptrSym = r.Int64()
+
+		// Interfaces have no method extension data; their methods
+		// belong to the underlying type.
+		if len(obj.methods) > 0 {
+			ext.funcs = make([]*funcExt, len(obj.methods))
+			for i, m := range obj.methods {
+				ext.funcs[i] = r.funcExt(1 + len(m.sig.params))
+			}
+		}
+
+	case pkgbits.ObjVar:
+		r.Sync(pkgbits.SyncVarExt)
+		ext.link = r.linkname()
+	}
+
+	return ext
+}
+
+// funcExt reads the extension data of a function with the given number
+// of receiver and parameter values.
+func (r *reader) funcExt(nparams int) *funcExt {
+	r.Sync(pkgbits.SyncFuncExt)
+
+	ext := &funcExt{
+		pragma: r.pragmaFlag(),
+		link:   r.linkname(),
+	}
+
+	if r.p.goarch == "wasm" {
+		ext.wasmImport[0] = r.String()
+		ext.wasmImport[1] = r.String()
+		ext.wasmExport = r.String()
+	}
+
+	if ext.extended = r.Bool(); ext.extended {
+		ext.abi = r.Uint64()
+		ext.escapes = make([]string, nparams)
+		for i := range ext.escapes {
+			ext.escapes[i] = r.String()
+		}
+		if r.Bool() {
+			ext.inl = &inlineInfo{
+				cost:            r.Len(),
+				canDelayResults: r.Bool(),
+			}
+			if r.p.newInliner {
+				ext.inl.properties = r.String()
+			}
+		}
+	} else {
+		ext.body = r.Reloc(pkgbits.SectionBody)
+	}
+
+	r.Sync(pkgbits.SyncEOF)
+	return ext
+}
+
+func (r *reader) pragmaFlag() pragmaFlag {
+	r.Sync(pkgbits.SyncPragma)
+	return pragmaFlag(r.Int())
+}
+
+func (r *reader) linkname() linkInfo {
+	r.Sync(pkgbits.SyncLinkname)
+	info := linkInfo{symIdx: r.Int64()}
+	if info.symIdx < 0 {
+		info.linkname = r.String()
+		info.std = r.Bool()
+	}
+	return info
+}
+
+// @@@ Formatting
+
+// funcSymName returns the linker symbol name of obj, or of its method
+// m if non-nil, as used by the private root's body list.
+func (pr *pkgReader) funcSymName(obj *objNode, m *methodNode) string {
+	path := pr.pkgIdx(obj.pkg).path
+	if m == nil {
+		return path + "." + obj.name
+	}
+	if recv := pr.typIdx(m.recv.typ, obj.dict); recv != nil && recv.code == pkgbits.TypePointer {
+		return fmt.Sprintf("%s.(*%s).%s", path, obj.name, m.name)
+	}
+	return fmt.Sprintf("%s.%s.%s", path, obj.name, m.name)
+}
+
+// paramNames returns the names of the receiver, if any, and parameters
+// of a function.
+func paramNames(recv *param, sig *signature) []string {
+	var names []string
+	if recv != nil {
+		names = append(names, recv.name)
+	}
+	for _, p := range sig.params {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// funcExtString describes ext, the extension data of the function
+// whose linker symbol is sym. names holds the names of its receiver
+// and parameters, for labelling escape analysis notes.
+func (pr *pkgReader) funcExtString(ext *funcExt, sym string, names []string) string {
+	var parts []string
+	if ext.pragma != 0 {
+		parts = append(parts, ext.pragma.String())
+	}
+	if s := ext.link.String(); s != "" {
+		parts = append(parts, s)
+	}
+	if ext.wasmImport[0] != "" || ext.wasmImport[1] != "" {
+		parts = append(parts, fmt.Sprintf("wasmimport %s %s", ext.wasmImport[0], ext.wasmImport[1]))
+	}
+	if ext.wasmExport != "" {
+		parts = append(parts, "wasmexport "+ext.wasmExport)
+	}
+
+	if !ext.extended {
+		parts = append(parts, fmt.Sprintf("body: SectionBody[%d] (compiled by importers)", ext.body))
+		return strings.Join(parts, ", ")
+	}
+
+	parts = append(parts, abiName(ext.abi))
+	if ext.inl != nil {
+		s := fmt.Sprintf("inlinable (cost %d", ext.inl.cost)
+		if ext.inl.canDelayResults {
+			s += ", can delay results"
+		}
+		if ext.inl.properties != "" {
+			s += ", properties " + ext.inl.properties
+		}
+		parts = append(parts, s+")")
+	} else {
+		parts = append(parts, "not inlinable")
+	}
+	if idx, ok := pr.bodies[sym]; ok {
+		parts = append(parts, fmt.Sprintf("body: SectionBody[%d]", idx))
+	} else {
+		parts = append(parts, "no body")
+	}
+	if len(ext.escapes) > 0 {
+		notes := make([]string, len(ext.escapes))
+		for i, note := range ext.escapes {
+			name := fmt.Sprintf("#%d", i)
+			if i < len(names) && names[i] != "" {
+				name = names[i]
+			}
+			notes[i] = name + " " + escapeString(note)
+		}
+		parts = append(parts, "escapes: "+strings.Join(notes, "; "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// escapeString describes an escape analysis note, as encoded by
+// cmd/compile's escape.leaks.Encode: "esc:" followed by one byte per
+// destination (heap, mutator, callee, then results) holding the
+// minimum dereference count plus one, or zero if there is no flow. An
+// empty note is written both for parameters without pointers and for
+// those leaking directly to the heap.
+func escapeString(note string) string {
+	if !strings.HasPrefix(note, "esc:") {
+		return "has no pointers or leaks to heap"
+	}
+
+	var flows []string
+	for i, v := range []byte(note[len("esc:"):]) {
+		if v == 0 {
+			continue
+		}
+		var dst string
+		switch i {
+		case 0:
+			dst = "heap"
+		case 1:
+			dst = "mutator"
+		case 2:
+			dst = "callee"
+		default:
+			dst = fmt.Sprintf("result %d", i-3)
+		}
+		flows = append(flows, fmt.Sprintf("%s (level %d)", dst, v-1))
+	}
+	if len(flows) == 0 {
+		return "does not escape"
+	}
+	return "leaks to " + strings.Join(flows, " and ")
+}
+
+func (info linkInfo) String() string {
+	switch {
+	case info.symIdx >= 0:
+		return fmt.Sprintf("symbol #%d", info.symIdx)
+	case info.linkname == "":
+		return ""
+	case info.std:
+		return "//go:linkname " + info.linkname + " (std)"
+	}
+	return "//go:linkname " + info.linkname
+}
+
+// abiName returns the name of a cmd/internal/obj ABI value.
+func abiName(abi uint64) string {
+	switch abi {
+	case 0:
+		return "ABI0"
+	case 1:
+		return "ABIInternal"
+	}
+	return fmt.Sprintf("ABI(%d)", abi)
+}
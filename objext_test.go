@@ -0,0 +1,64 @@
+package main
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+func TestFuncExtString(t *testing.T) {
+	pr := testReader(t, examplePackage())
+
+	idx, person := exampleObj(t, pr, "Person")
+	ext := pr.objExtIdx(idx, person)
+	if ext.typeSym != 3 || ext.ptrSym != 4 {
+		t.Errorf("Person has type symbols %d and %d, want 3 and 4", ext.typeSym, ext.ptrSym)
+	}
+	m := person.methods[0]
+	got := pr.funcExtString(ext.funcs[0], pr.funcSymName(person, m), paramNames(&m.recv, m.sig))
+	want := "symbol #0, ABIInternal, inlinable (cost 7, can delay results), body: SectionBody[1], escapes: p leaks to result 0 (level 0)"
+	if got != want {
+		t.Errorf("Person.String has extension data\n%s\nwant\n%s", got, want)
+	}
+
+	for name, want := range map[string]string{
+		"Sum":      "symbol #0, body: SectionBody[0] (compiled by importers)",
+		"Describe": "symbol #0, ABIInternal, not inlinable, body: SectionBody[2], escapes: p has no pointers or leaks to heap",
+	} {
+		idx, obj := exampleObj(t, pr, name)
+		ext := pr.objExtIdx(idx, obj)
+		got := pr.funcExtString(ext.funcs[0], pr.funcSymName(obj, nil), paramNames(nil, obj.sig))
+		if got != want {
+			t.Errorf("%s has extension data\n%s\nwant\n%s", name, got, want)
+		}
+	}
+}
+
+func TestPragmaLinkname(t *testing.T) {
+	w := uirtest.New(pkgbits.V2, -1, testPath, "example")
+	fn := w.NewObject(w.Self, "nanotime", pkgbits.ObjFunc)
+	fn.Sig = &uirtest.Signature{Results: []uirtest.Param{{Type: w.Basic(types.Int64)}}}
+	fn.FuncExt = &uirtest.FuncExt{
+		Pragma: 1<<3 | 1<<4 | 1<<20, // nosplit, noinline and an unknown flag
+		Link:   uirtest.Link{SymIdx: -1, Linkname: "runtime.nanotime", Std: true},
+	}
+	v := w.NewObject(w.Self, "x", pkgbits.ObjVar)
+	v.Type = w.Basic(types.Int64)
+	v.Link = uirtest.Link{SymIdx: -1, Linkname: "runtime.x"}
+	fn.Flush()
+	v.Flush()
+
+	pr := testReader(t, w.Bytes())
+	obj := pr.objIdx(fn.Idx)
+	ext := pr.objExtIdx(fn.Idx, obj)
+	got := pr.funcExtString(ext.funcs[0], pr.funcSymName(obj, nil), nil)
+	want := "//go:nosplit //go:noinline 0x100000, //go:linkname runtime.nanotime (std), ABI0, not inlinable, no body"
+	if got != want {
+		t.Errorf("nanotime has extension data\n%s\nwant\n%s", got, want)
+	}
+	if got, want := pr.objExtIdx(v.Idx, pr.objIdx(v.Idx)).link.String(), "//go:linkname runtime.x"; got != want {
+		t.Errorf("x is linked by %q, want %q", got, want)
+	}
+}
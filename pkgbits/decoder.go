@@ -5,6 +5,7 @@
 package pkgbits
 
 import (
+	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -12,8 +13,8 @@ import (
 	"go/token"
 	"io"
 	"math/big"
-	"os"
 	"runtime"
+	"slices"
 	"strings"
 )
 
@@ -31,6 +32,10 @@ type PkgDecoder struct {
 	// TODO(mdempsky): Remove; unneeded since CL 391014.
 	pkgPath string
 
+	// header is the raw encoding of the version, flags and element
+	// ends that precede elemData, kept to recompute the fingerprint.
+	header string
+
 	// elemData is the full data payload of the encoded package.
 	// Elements are densely and contiguously packed together.
 	//
@@ -55,6 +60,12 @@ type PkgDecoder struct {
 	elemEndsEnds [numRelocs]uint32
 
 	scratchRelocEnt []RefTableEntry
+
+	// traces, if non-nil, collects the traces of the Decoders for
+	// element (traceKind, traceIdx). See TraceElem.
+	traces    *[]*ElemTrace
+	traceKind SectionKind
+	traceIdx  RelElemIdx
 }
 
 // PkgPath returns the package path for the package
@@ -65,47 +76,147 @@ func (pr *PkgDecoder) PkgPath() string { return pr.pkgPath }
 // SyncMarkers reports whether pr uses sync markers.
 func (pr *PkgDecoder) SyncMarkers() bool { return pr.sync }
 
+// Version reports the version of the bitstream.
+func (pr *PkgDecoder) Version() Version { return pr.version }
+
 // NewPkgDecoder returns a PkgDecoder initialized to read the Unified
 // IR export data from input. pkgPath is the package path for the
 // compilation unit that produced the export data.
+//
+// NewPkgDecoder panics if input is malformed; see ParsePkgDecoder.
 func NewPkgDecoder(pkgPath, input string) PkgDecoder {
-	pr := PkgDecoder{
-		pkgPath: pkgPath,
+	pr, err := ParsePkgDecoder(pkgPath, input)
+	if err != nil {
+		panic(err)
 	}
+	return *pr
+}
 
-	// TODO(mdempsky): Implement direct indexing of input string to
-	// avoid copying the position information.
+// ParsePkgDecoder is like NewPkgDecoder, but returns an error if the
+// header of input is truncated or inconsistent. The error names the
+// offending header field and its byte offset within input.
+func ParsePkgDecoder(pkgPath, input string) (*PkgDecoder, error) {
+	pr := &PkgDecoder{
+		pkgPath: pkgPath,
+	}
 
-	r := strings.NewReader(input)
+	// The values are checked in the order they are read, before any
+	// error HeaderFields gives for the fields that follow.
+	fields, headerErr := HeaderFields(input)
+	for _, f := range fields {
+		switch f.Name {
+		case "version":
+			pr.version = Version(f.Value)
+			if pr.version >= numVersions {
+				return nil, fmt.Errorf("cannot decode %q, export data version %d is greater than maximum supported version %d", pkgPath, pr.version, numVersions-1)
+			}
+		case "flags":
+			pr.sync = f.Value&flagSyncMarkers != 0
+		case "elemEndsEnds":
+			k := f.Index
+			if k > 0 && f.Value < pr.elemEndsEnds[k-1] {
+				return nil, fmt.Errorf("cannot decode %q: bad section count: elemEndsEnds[%v] at offset %d is %d, less than the previous section's %d", pkgPath, SectionKind(k), f.Offset, f.Value, pr.elemEndsEnds[k-1])
+			}
+			pr.elemEndsEnds[k] = f.Value
+			if k == len(pr.elemEndsEnds)-1 && f.Value == 0 {
+				return nil, fmt.Errorf("cannot decode %q: bad section count: no elements", pkgPath)
+			}
+		case "elemEnds":
+			if pr.elemEnds == nil {
+				pr.elemEnds = make([]uint32, pr.elemEndsEnds[len(pr.elemEndsEnds)-1])
+			}
+			pr.elemEnds[f.Index] = f.Value
+		}
+	}
+	if headerErr != nil {
+		return nil, fmt.Errorf("cannot decode %q: %w", pkgPath, headerErr)
+	}
 
-	var ver uint32
-	assert(binary.Read(r, binary.LittleEndian, &ver) == nil)
-	pr.version = Version(ver)
+	off := 4 * len(fields)
+	pr.header = input[:off]
+	pr.elemData = input[off:]
 
-	if pr.version >= numVersions {
-		panic(fmt.Errorf("cannot decode %q, export data version %d is greater than maximum supported version %d", pkgPath, pr.version, numVersions-1))
+	const fingerprintSize = 8
+	if want := int64(pr.elemEnds[len(pr.elemEnds)-1]) + fingerprintSize; int64(len(pr.elemData)) != want {
+		return nil, fmt.Errorf("cannot decode %q: element data at offset %d is %d bytes, want %d (last element end %d plus %d-byte fingerprint)", pkgPath, off, len(pr.elemData), want, pr.elemEnds[len(pr.elemEnds)-1], fingerprintSize)
 	}
 
-	if pr.version.Has(Flags) {
-		var flags uint32
-		assert(binary.Read(r, binary.LittleEndian, &flags) == nil)
-		pr.sync = flags&flagSyncMarkers != 0
+	return pr, nil
+}
+
+// A HeaderField is one of the little-endian uint32 fields that make up
+// the header of the export data, before the element data.
+type HeaderField struct {
+	Name   string // "version", "flags", "elemEndsEnds" or "elemEnds"
+	Index  int    // the section of elemEndsEnds, or the element of elemEnds
+	Offset int    // within the export data
+	Value  uint32
+}
+
+// HeaderFields returns the fields of the header at the start of input,
+// in order. It doesn't check their values beyond what it takes to know
+// which fields follow, so that a corrupt header can be inspected; see
+// ParsePkgDecoder for the checks. If input ends within the header,
+// HeaderFields returns the fields before that point and a
+// *TruncatedHeaderError.
+func HeaderFields(input string) ([]HeaderField, error) {
+	var fields []HeaderField
+	off := 0
+	read := func(name string, index int) error {
+		if len(input)-off < 4 {
+			field := name
+			if name == "elemEndsEnds" {
+				field = fmt.Sprintf("%s[%v]", name, SectionKind(index))
+			}
+			return &TruncatedHeaderError{Field: field, Offset: off, Need: 4, Have: len(input) - off}
+		}
+		v := binary.LittleEndian.Uint32([]byte(input[off : off+4]))
+		fields = append(fields, HeaderField{Name: name, Index: index, Offset: off, Value: v})
+		off += 4
+		return nil
 	}
 
-	assert(binary.Read(r, binary.LittleEndian, pr.elemEndsEnds[:]) == nil)
-
-	pr.elemEnds = make([]uint32, pr.elemEndsEnds[len(pr.elemEndsEnds)-1])
-	assert(binary.Read(r, binary.LittleEndian, pr.elemEnds[:]) == nil)
+	if err := read("version", 0); err != nil {
+		return fields, err
+	}
+	if Version(fields[0].Value).Has(Flags) {
+		if err := read("flags", 0); err != nil {
+			return fields, err
+		}
+	}
 
-	pos, err := r.Seek(0, io.SeekCurrent)
-	assert(err == nil)
+	for k := range numRelocs {
+		if err := read("elemEndsEnds", int(k)); err != nil {
+			return fields, err
+		}
+	}
 
-	pr.elemData = input[pos:]
+	// Check the element count against the input size before
+	// allocating, so a corrupt count can't exhaust memory.
+	total := fields[len(fields)-1].Value
+	if uint64(total)*4 > uint64(len(input)-off) {
+		field := fmt.Sprintf("elemEnds for %d elements", total)
+		return fields, &TruncatedHeaderError{Field: field, Offset: off, Need: 4 * int(total), Have: len(input) - off}
+	}
+	fields = slices.Grow(fields, int(total))
+	for i := range int(total) {
+		// The size check above guarantees these reads succeed.
+		read("elemEnds", i)
+	}
+	return fields, nil
+}
 
-	const fingerprintSize = 8
-	assert(len(pr.elemData)-fingerprintSize == int(pr.elemEnds[len(pr.elemEnds)-1]))
+// A TruncatedHeaderError reports that export data ends within its
+// header.
+type TruncatedHeaderError struct {
+	Field  string // such as "elemEndsEnds[SectionPkg]"
+	Offset int    // of the field, within the export data
+	Need   int    // bytes the field takes
+	Have   int    // bytes left in the export data
+}
 
-	return pr
+func (e *TruncatedHeaderError) Error() string {
+	return fmt.Sprintf("truncated header: %s at offset %d needs %d bytes, have %d", e.Field, e.Offset, e.Need, e.Have)
 }
 
 // NumElems returns the number of elements in section k.
@@ -129,6 +240,109 @@ func (pr *PkgDecoder) Fingerprint() [8]byte {
 	return fp
 }
 
+// Validate checks the structure of the export data and returns every
+// defect found, or nil if there are none. ParsePkgDecoder has already
+// checked the section ends and the size of the element data; Validate
+// checks that element end offsets are monotonic and within the element
+// data, and that each element's reference table decodes and only
+// refers to elements that exist. See ComputeFingerprint to check the
+// fingerprint's value.
+//
+// Validate does not decode the elements themselves, which requires
+// knowing what each section's elements contain.
+func (pr *PkgDecoder) Validate() []error {
+	var errs []error
+	report := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	const fingerprintSize = 8
+	dataLen := len(pr.elemData) - fingerprintSize
+	var prev uint32
+	sane := true
+	for i, end := range pr.elemEnds {
+		if end < prev {
+			report("elemEnds[%d] is %d, less than elemEnds[%d] (%d)", i, end, i-1, prev)
+			sane = false
+		} else if int(end) > dataLen {
+			report("elemEnds[%d] is %d, past the end of the element data (%d bytes)", i, end, dataLen)
+			sane = false
+		}
+		prev = end
+	}
+
+	// Element boundaries must be sound before looking inside elements.
+	if !sane {
+		return errs
+	}
+
+	for k := SectionKind(0); k < numRelocs; k++ {
+		// String elements are raw data, without a reference table.
+		if k == SectionString {
+			continue
+		}
+		for i := range pr.NumElems(k) {
+			errs = append(errs, pr.validateRelocs(k, RelElemIdx(i))...)
+		}
+	}
+
+	return errs
+}
+
+// validateRelocs checks the reference table of the given element.
+func (pr *PkgDecoder) validateRelocs(k SectionKind, idx RelElemIdx) (errs []error) {
+	defer func() {
+		if r := recover(); r != nil {
+			errs = append(errs, fmt.Errorf("%v[%d]: cannot decode reference table: %v", k, idx, r))
+		}
+	}()
+
+	r := pr.NewDecoderRaw(k, idx)
+	for i, ent := range r.Relocs {
+		switch {
+		case ent.Kind < 0 || ent.Kind >= numRelocs:
+			errs = append(errs, fmt.Errorf("%v[%d]: reference %d has invalid section %v", k, idx, i, ent.Kind))
+		case ent.Idx < 0 || int(ent.Idx) >= pr.NumElems(ent.Kind):
+			errs = append(errs, fmt.Errorf("%v[%d]: reference %d points to %v[%d], but the section has %d elements", k, idx, i, ent.Kind, ent.Idx, pr.NumElems(ent.Kind)))
+		}
+	}
+	return errs
+}
+
+// ComputeFingerprint recomputes the package fingerprint from the
+// header and element data, as PkgEncoder.DumpTo does. It differs from
+// Fingerprint if the export data was corrupted or edited.
+func (pr *PkgDecoder) ComputeFingerprint() [8]byte {
+	const fingerprintSize = 8
+	return hashFingerprint(pr.header, pr.elemData[:len(pr.elemData)-fingerprintSize])
+}
+
+// InputFingerprints returns the fingerprint stored at the end of input
+// and the one computed from the rest of it, without parsing the header,
+// so that export data whose header is corrupt can still be checked. ok
+// is false if input is too short to hold a fingerprint.
+func InputFingerprints(input string) (stored, computed [8]byte, ok bool) {
+	const fingerprintSize = 8
+	if len(input) < fingerprintSize {
+		return stored, computed, false
+	}
+	copy(stored[:], input[len(input)-fingerprintSize:])
+	return stored, hashFingerprint(input[:len(input)-fingerprintSize]), true
+}
+
+// hashFingerprint returns the fingerprint of the export data made up of
+// parts, which exclude the fingerprint itself.
+func hashFingerprint(parts ...string) [8]byte {
+	h := sha256.New()
+	for _, part := range parts {
+		io.WriteString(h, part)
+	}
+
+	var fp [8]byte
+	copy(fp[:], h.Sum(nil))
+	return fp
+}
+
 // AbsIdx returns the absolute index for the given (section, index)
 // pair.
 func (pr *PkgDecoder) AbsIdx(k SectionKind, idx RelElemIdx) int {
@@ -153,6 +367,12 @@ func (pr *PkgDecoder) DataIdx(k SectionKind, idx RelElemIdx) string {
 	}
 	end := pr.elemEnds[absIdx]
 
+	// Corrupt element ends would otherwise fail as a runtime error.
+	const fingerprintSize = 8
+	if size := len(pr.elemData) - fingerprintSize; start > end || int(end) > size {
+		panicf("%v:%v has bad bounds [%d:%d] in %d bytes of element data", k, idx, start, end, size)
+	}
+
 	return pr.elemData[start:end]
 }
 
@@ -192,14 +412,20 @@ func (pr *PkgDecoder) NewDecoderRaw(k SectionKind, idx RelElemIdx) Decoder {
 		common: pr,
 		k:      k,
 		Idx:    idx,
+		trace:  pr.newTrace(k, idx),
 	}
 
 	r.Data.Reset(pr.DataIdx(k, idx))
 	r.Sync(SyncRelocs)
 	r.Relocs = make([]RefTableEntry, r.Len())
+	if r.trace != nil {
+		r.traceNote("reference table length")
+	}
 	for i := range r.Relocs {
-		r.Sync(SyncReloc)
-		r.Relocs[i] = RefTableEntry{SectionKind(r.Len()), RelElemIdx(r.Len())}
+		r.readRelocEnt(i)
+	}
+	if r.trace != nil {
+		r.trace.RelocsEnd = r.pos()
 	}
 
 	return r
@@ -210,11 +436,15 @@ func (pr *PkgDecoder) TempDecoderRaw(k SectionKind, idx RelElemIdx) Decoder {
 		common: pr,
 		k:      k,
 		Idx:    idx,
+		trace:  pr.newTrace(k, idx),
 	}
 
 	r.Data.Reset(pr.DataIdx(k, idx))
 	r.Sync(SyncRelocs)
 	l := r.Len()
+	if r.trace != nil {
+		r.traceNote("reference table length")
+	}
 	if cap(pr.scratchRelocEnt) >= l {
 		r.Relocs = pr.scratchRelocEnt[:l]
 		pr.scratchRelocEnt = nil
@@ -222,13 +452,29 @@ func (pr *PkgDecoder) TempDecoderRaw(k SectionKind, idx RelElemIdx) Decoder {
 		r.Relocs = make([]RefTableEntry, l)
 	}
 	for i := range r.Relocs {
-		r.Sync(SyncReloc)
-		r.Relocs[i] = RefTableEntry{SectionKind(r.Len()), RelElemIdx(r.Len())}
+		r.readRelocEnt(i)
+	}
+	if r.trace != nil {
+		r.trace.RelocsEnd = r.pos()
 	}
 
 	return r
 }
 
+// readRelocEnt reads entry i of the reference table.
+func (r *Decoder) readRelocEnt(i int) {
+	r.Sync(SyncReloc)
+	k := SectionKind(r.Len())
+	if r.trace != nil {
+		r.traceNote("reference %d section: %v", i, k)
+	}
+	idx := RelElemIdx(r.Len())
+	if r.trace != nil {
+		r.traceNote("reference %d index", i)
+	}
+	r.Relocs[i] = RefTableEntry{k, idx}
+}
+
 // A Decoder provides methods for decoding an individual element's
 // bitstream data.
 type Decoder struct {
@@ -239,6 +485,8 @@ type Decoder struct {
 
 	k   SectionKind
 	Idx RelElemIdx
+
+	trace *ElemTrace // if non-nil, records the values read
 }
 
 func (r *Decoder) checkErr(err error) {
@@ -299,7 +547,8 @@ func (r *Decoder) rawReloc(k SectionKind, idx int) RelElemIdx {
 }
 
 // Sync decodes a sync marker from the element bitstream and asserts
-// that it matches the expected marker.
+// that it matches the expected marker, panicking with a *DesyncError
+// if not.
 //
 // If EnableSync is false, then Sync is a no-op.
 func (r *Decoder) Sync(mWant SyncMarker) {
@@ -313,11 +562,68 @@ func (r *Decoder) Sync(mWant SyncMarker) {
 	for i := range writerPCs {
 		writerPCs[i] = int(r.rawUvarint())
 	}
+	if r.trace != nil {
+		r.traceRead(int(pos), "sync", mHave.String())
+		if len(writerPCs) != 0 {
+			r.traceNote("%d writer frames", len(writerPCs))
+		}
+	}
 
 	if mHave == mWant {
 		return
 	}
+	if r.trace != nil {
+		r.traceNote("expected %v", mWant)
+	}
 
+	err := &DesyncError{
+		PkgPath:  r.common.pkgPath,
+		Section:  r.k,
+		Index:    r.Idx,
+		Offset:   pos,
+		Found:    mHave,
+		Expected: mWant,
+	}
+	for _, pc := range writerPCs {
+		err.WriterFrames = append(err.WriterFrames, r.common.StringIdx(r.rawReloc(SectionString, pc)))
+	}
+	var readerPCs [32]uintptr // TODO(mdempsky): Dynamically size?
+	n := runtime.Callers(2, readerPCs[:])
+	err.ReaderFrames = fmtFrames(readerPCs[:n]...)
+
+	panic(err)
+}
+
+// A DesyncError reports that an element bitstream contained a
+// different sync marker than the reader expected, meaning that the
+// reader and writer disagree about the element's layout. Decoder.Sync
+// panics with a *DesyncError, which callers may recover to skip the
+// element.
+type DesyncError struct {
+	PkgPath string
+	Section SectionKind
+	Index   RelElemIdx
+	Offset  int64 // byte offset of the marker within the element
+
+	Found    SyncMarker
+	Expected SyncMarker
+
+	// WriterFrames holds the writer's call frames for the found
+	// marker, as "file:line: function +offset" strings. It is empty
+	// unless the package was compiled with -d=syncframes.
+	WriterFrames []string
+
+	// ReaderFrames holds the reader's call frames, in the same format.
+	ReaderFrames []string
+}
+
+func (e *DesyncError) Error() string {
+	return fmt.Sprintf("export data desync: package %q, section %v, index %v, offset %v: found %v, expected %v", e.PkgPath, e.Section, e.Index, e.Offset, e.Found, e.Expected)
+}
+
+// Report returns a multi-line description of e, including the writer
+// and reader call frames.
+func (e *DesyncError) Report() string {
 	// There's some tension here between printing:
 	//
 	// (1) full file paths that tools can recognize (e.g., so emacs
@@ -334,34 +640,33 @@ func (r *Decoder) Sync(mWant SyncMarker) {
 	// "$GOROOT" (like objabi.AbsFile does) if tools can be taught how
 	// to reliably expand that again.
 
-	fmt.Printf("export data desync: package %q, section %v, index %v, offset %v\n", r.common.pkgPath, r.k, r.Idx, pos)
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "export data desync: package %q, section %v, index %v, offset %v\n", e.PkgPath, e.Section, e.Index, e.Offset)
 
-	fmt.Printf("\nfound %v, written at:\n", mHave)
-	if len(writerPCs) == 0 {
-		fmt.Printf("\t[stack trace unavailable; recompile package %q with -d=syncframes]\n", r.common.pkgPath)
+	fmt.Fprintf(&buf, "\nfound %v, written at:\n", e.Found)
+	if len(e.WriterFrames) == 0 {
+		fmt.Fprintf(&buf, "\t[stack trace unavailable; recompile package %q with -d=syncframes]\n", e.PkgPath)
 	}
-	for _, pc := range writerPCs {
-		fmt.Printf("\t%s\n", r.common.StringIdx(r.rawReloc(SectionString, pc)))
+	for _, frame := range e.WriterFrames {
+		fmt.Fprintf(&buf, "\t%s\n", frame)
 	}
 
-	fmt.Printf("\nexpected %v, reading at:\n", mWant)
-	var readerPCs [32]uintptr // TODO(mdempsky): Dynamically size?
-	n := runtime.Callers(2, readerPCs[:])
-	for _, pc := range fmtFrames(readerPCs[:n]...) {
-		fmt.Printf("\t%s\n", pc)
+	fmt.Fprintf(&buf, "\nexpected %v, reading at:\n", e.Expected)
+	for _, frame := range e.ReaderFrames {
+		fmt.Fprintf(&buf, "\t%s\n", frame)
 	}
-
-	// We already printed a stack trace for the reader, so now we can
-	// simply exit. Printing a second one with panic or base.Fatalf
-	// would just be noise.
-	os.Exit(1)
+	return buf.String()
 }
 
 // Bool decodes and returns a bool value from the element bitstream.
 func (r *Decoder) Bool() bool {
 	r.Sync(SyncBool)
+	pos := r.pos()
 	x, err := r.Data.ReadByte()
 	r.checkErr(err)
+	if r.trace != nil {
+		r.traceRead(pos, "bool", fmt.Sprint(x != 0))
+	}
 	assert(x < 2)
 	return x != 0
 }
@@ -369,13 +674,23 @@ func (r *Decoder) Bool() bool {
 // Int64 decodes and returns an int64 value from the element bitstream.
 func (r *Decoder) Int64() int64 {
 	r.Sync(SyncInt64)
-	return r.rawVarint()
+	pos := r.pos()
+	x := r.rawVarint()
+	if r.trace != nil {
+		r.traceRead(pos, "zvarint", fmt.Sprint(x))
+	}
+	return x
 }
 
 // Uint64 decodes and returns a uint64 value from the element bitstream.
 func (r *Decoder) Uint64() uint64 {
 	r.Sync(SyncUint64)
-	return r.rawUvarint()
+	pos := r.pos()
+	x := r.rawUvarint()
+	if r.trace != nil {
+		r.traceRead(pos, "uvarint", fmt.Sprint(x))
+	}
+	return x
 }
 
 // Len decodes and returns a non-negative int value from the element bitstream.
@@ -403,14 +718,22 @@ func (r *Decoder) Code(mark SyncMarker) int {
 // bitstream and returns an index to the referenced element.
 func (r *Decoder) Reloc(k SectionKind) RelElemIdx {
 	r.Sync(SyncUseReloc)
-	return r.rawReloc(k, r.Len())
+	i := r.Len()
+	if r.trace != nil && i < len(r.Relocs) {
+		r.traceNote("reference %d: %v:%d", i, r.Relocs[i].Kind, r.Relocs[i].Idx)
+	}
+	return r.rawReloc(k, i)
 }
 
 // String decodes and returns a string value from the element
 // bitstream.
 func (r *Decoder) String() string {
 	r.Sync(SyncString)
-	return r.common.StringIdx(r.Reloc(SectionString))
+	s := r.common.StringIdx(r.Reloc(SectionString))
+	if r.trace != nil {
+		r.traceNote("%q", s)
+	}
+	return s
 }
 
 // Strings decodes and returns a variable-length slice of strings from
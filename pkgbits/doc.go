@@ -6,6 +6,34 @@
 The Unified IR (UIR) format for primitive types is implicitly defined by the
 package pkgbits.
 
+# Export data
+The export data of a package is a header that locates the bitstream of each
+element, followed by the bitstreams themselves and a fingerprint of it all.
+In archives and object files, it is preceded by the byte 'u', which marks it
+as Unified IR for importers. All uint32s are little endian.
+
+       ExportData   = Version
+                      [ Flags ]    // from V1
+                      ElemEndsEnds
+                      ElemEnds
+                      ElemData
+                      Fingerprint
+                      .
+
+       Version      = uint32 .
+       Flags        = uint32 .     // bit 0 set if the elements contain Syncs
+
+The elements are grouped into sections, in the order of SectionKind. For each
+section, ElemEndsEnds holds the number of elements up to the section's end, and
+ElemEnds holds the offset within ElemData at which each element's bitstream
+ends. Each bitstream starts where the previous one ends.
+
+       ElemEndsEnds = [ 10 ]uint32 .
+       ElemEnds     = { uint32 } . // as many as the last of ElemEndsEnds
+       ElemData     = { byte } .   // as many as the last of ElemEnds
+       Fingerprint  = [ 8 ]byte .  // from SHA-256 of all that precedes it
+
+# Primitives
 The most basic primitives are laid out as below.
 
        Bool    = [ Sync ] byte .
@@ -7,3 +7,7 @@ package pkgbits
 const (
 	flagSyncMarkers = 1 << iota // file format contains sync markers
 )
+
+// FlagSyncMarkers is the bit of the header's flags field that is set if
+// the elements contain sync markers. See HeaderFields.
+const FlagSyncMarkers = flagSyncMarkers
@@ -5,9 +5,11 @@
 package pkgbits_test
 
 import (
-	"internal/pkgbits"
+	"errors"
 	"strings"
 	"testing"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
 )
 
 func TestRoundTrip(t *testing.T) {
@@ -33,6 +35,253 @@ func TestRoundTrip(t *testing.T) {
 	}
 }
 
+func TestParseTruncated(t *testing.T) {
+	pw := pkgbits.NewPkgEncoder(pkgbits.V2, -1)
+	w := pw.NewEncoder(pkgbits.SectionMeta, pkgbits.SyncPublic)
+	w.Flush()
+
+	var b strings.Builder
+	_ = pw.DumpTo(&b)
+	input := b.String()
+
+	if _, err := pkgbits.ParsePkgDecoder("package_id", input); err != nil {
+		t.Fatalf("ParsePkgDecoder failed on valid input: %v", err)
+	}
+	for n := range len(input) {
+		if _, err := pkgbits.ParsePkgDecoder("package_id", input[:n]); err == nil {
+			t.Errorf("ParsePkgDecoder succeeded on input truncated to %d of %d bytes", n, len(input))
+		}
+	}
+
+	_, err := pkgbits.ParsePkgDecoder("package_id", input[:10])
+	if want := "elemEndsEnds[SectionString] at offset 8"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("ParsePkgDecoder error = %v, want mention of %q", err, want)
+	}
+}
+
+func TestHeaderFields(t *testing.T) {
+	pw := pkgbits.NewPkgEncoder(pkgbits.V2, 0)
+	w := pw.NewEncoder(pkgbits.SectionMeta, pkgbits.SyncPublic)
+	w.Flush()
+
+	var b strings.Builder
+	_ = pw.DumpTo(&b)
+	input := b.String()
+
+	fields, err := pkgbits.HeaderFields(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// version, flags, 10 elemEndsEnds and the one element's elemEnds.
+	if len(fields) != 13 {
+		t.Fatalf("got %d fields, want 13: %+v", len(fields), fields)
+	}
+	if f := fields[1]; f.Name != "flags" || f.Offset != 4 || f.Value != pkgbits.FlagSyncMarkers {
+		t.Errorf("second field is %+v, want flags with sync markers", f)
+	}
+	if f := fields[12]; f.Name != "elemEnds" || f.Index != 0 || f.Offset != 48 {
+		t.Errorf("last field is %+v, want elemEnds[0] at offset 48", f)
+	}
+
+	fields, err = pkgbits.HeaderFields(input[:10])
+	var truncated *pkgbits.TruncatedHeaderError
+	if len(fields) != 2 || !errors.As(err, &truncated) || truncated.Field != "elemEndsEnds[SectionString]" || truncated.Have != 2 {
+		t.Errorf("HeaderFields of 10 bytes = %+v, %v, want 2 fields and a truncated elemEndsEnds[SectionString]", fields, err)
+	}
+}
+
+func TestDesync(t *testing.T) {
+	pw := pkgbits.NewPkgEncoder(pkgbits.V2, 0)
+	w := pw.NewEncoder(pkgbits.SectionMeta, pkgbits.SyncPublic)
+	w.Bool(true)
+	w.Flush()
+
+	var b strings.Builder
+	_ = pw.DumpTo(&b)
+
+	pr := pkgbits.NewPkgDecoder("package_id", b.String())
+	r := pr.NewDecoder(pkgbits.SectionMeta, pkgbits.PublicRootIdx, pkgbits.SyncPublic)
+
+	defer func() {
+		x := recover()
+		err, ok := x.(*pkgbits.DesyncError)
+		if !ok {
+			t.Fatalf("expected a *DesyncError panic, got %v", x)
+		}
+		if err.Section != pkgbits.SectionMeta || err.Found != pkgbits.SyncBool || err.Expected != pkgbits.SyncInt64 {
+			t.Errorf("unexpected desync: %v", err)
+		}
+	}()
+	r.Int64()
+}
+
+func TestComputeFingerprint(t *testing.T) {
+	pw := pkgbits.NewPkgEncoder(pkgbits.V2, -1)
+	w := pw.NewEncoder(pkgbits.SectionMeta, pkgbits.SyncPublic)
+	w.String("hello")
+	w.Flush()
+
+	var b strings.Builder
+	fp := pw.DumpTo(&b)
+	input := b.String()
+
+	pr := pkgbits.NewPkgDecoder("package_id", input)
+	if got := pr.ComputeFingerprint(); got != fp || got != pr.Fingerprint() {
+		t.Errorf("ComputeFingerprint = %x, want %x", got, fp)
+	}
+
+	i := strings.Index(input, "hello")
+	edited := input[:i] + "j" + input[i+1:]
+	pr = pkgbits.NewPkgDecoder("package_id", edited)
+	if got := pr.ComputeFingerprint(); got == pr.Fingerprint() {
+		t.Errorf("ComputeFingerprint matches the stored fingerprint after editing the data")
+	}
+
+	// InputFingerprints doesn't need a valid header.
+	if stored, computed, ok := pkgbits.InputFingerprints(input); !ok || stored != fp || computed != fp {
+		t.Errorf("InputFingerprints = %x, %x, %v, want %x twice", stored, computed, ok, fp)
+	}
+	broken := "\xff" + input[1:]
+	if stored, computed, ok := pkgbits.InputFingerprints(broken); !ok || stored != fp || computed == fp {
+		t.Errorf("InputFingerprints of a corrupt header = %x, %x, %v, want a mismatch", stored, computed, ok)
+	}
+	if _, _, ok := pkgbits.InputFingerprints("short"); ok {
+		t.Errorf("InputFingerprints of 5 bytes succeeded")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	pw := pkgbits.NewPkgEncoder(pkgbits.V2, -1)
+	w := pw.NewEncoder(pkgbits.SectionMeta, pkgbits.SyncPublic)
+	w.Reloc(pkgbits.SectionString, 0)
+	w.Flush()
+	pw.StringIdx("s")
+
+	var b strings.Builder
+	_ = pw.DumpTo(&b)
+	pr := pkgbits.NewPkgDecoder("package_id", b.String())
+	if errs := pr.Validate(); len(errs) != 0 {
+		t.Errorf("Validate reported defects in valid input: %v", errs)
+	}
+
+	w = pw.NewEncoder(pkgbits.SectionMeta, pkgbits.SyncPrivate)
+	w.Reloc(pkgbits.SectionType, 5)
+	w.Flush()
+
+	b.Reset()
+	_ = pw.DumpTo(&b)
+	pr = pkgbits.NewPkgDecoder("package_id", b.String())
+	errs := pr.Validate()
+	if want := "SectionMeta[1]: reference 0 points to SectionType[5], but the section has 0 elements"; len(errs) != 1 || errs[0].Error() != want {
+		t.Errorf("Validate = %v, want [%s]", errs, want)
+	}
+}
+
+func TestDataIdxBadBounds(t *testing.T) {
+	pw := pkgbits.NewPkgEncoder(pkgbits.V2, -1)
+	pw.StringIdx("s")
+	w := pw.NewEncoder(pkgbits.SectionMeta, pkgbits.SyncPublic)
+	w.Flush()
+
+	var b strings.Builder
+	_ = pw.DumpTo(&b)
+	input := []byte(b.String())
+	input[4+4+4*10] = 0xff // elemEnds[0], past the element data
+
+	pr, err := pkgbits.ParsePkgDecoder("package_id", string(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err, ok := recover().(error)
+		if want := "SectionString:0 has bad bounds"; !ok || !strings.Contains(err.Error(), want) {
+			t.Errorf("StringIdx panicked with %v, want an error mentioning %q", err, want)
+		}
+	}()
+	pr.StringIdx(0)
+}
+
+func TestTraceElem(t *testing.T) {
+	pw := pkgbits.NewPkgEncoder(pkgbits.V2, 0)
+	w := pw.NewEncoder(pkgbits.SectionMeta, pkgbits.SyncPublic)
+	w.String("hi")
+	w.Int64(-3)
+	w.Flush()
+
+	var b strings.Builder
+	_ = pw.DumpTo(&b)
+	pr := pkgbits.NewPkgDecoder("package_id", b.String())
+
+	var traces []*pkgbits.ElemTrace
+	pr.TraceElem(pkgbits.SectionMeta, pkgbits.PublicRootIdx, &traces)
+	r := pr.NewDecoder(pkgbits.SectionMeta, pkgbits.PublicRootIdx, pkgbits.SyncPublic)
+	if s, x := r.String(), r.Int64(); s != "hi" || x != -3 {
+		t.Fatalf("read %q, %d; want \"hi\", -3", s, x)
+	}
+	if len(traces) != 1 {
+		t.Fatalf("got %d traces, want 1", len(traces))
+	}
+	trace := traces[0]
+
+	var got []string
+	pos := 0
+	for _, e := range trace.Entries {
+		if e.Start != pos {
+			t.Errorf("entry %s %s starts at %d, want %d", e.Kind, e.Value, e.Start, pos)
+		}
+		pos = e.End
+		if e.Kind == "sync" {
+			if e.Start == trace.RelocsEnd && e.Value != "Public" {
+				t.Errorf("data starts with sync marker %s, want Public", e.Value)
+			}
+			continue
+		}
+		got = append(got, e.Kind+" "+e.Value+" "+strings.Join(e.Notes, "; "))
+	}
+	want := []string{
+		"uvarint 1 reference table length",
+		"uvarint 0 reference 0 section: SectionString",
+		"uvarint 0 reference 0 index",
+		`uvarint 0 reference 0: SectionString:0; "hi"`,
+		"zvarint -3 ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("traced values:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if pos != int(r.Data.Size()) {
+		t.Errorf("trace ends at %d, want %d", pos, r.Data.Size())
+	}
+}
+
+func TestDecodeAllocs(t *testing.T) {
+	pw := pkgbits.NewPkgEncoder(pkgbits.V2, 0)
+	w := pw.NewEncoder(pkgbits.SectionMeta, pkgbits.SyncPublic)
+	for range 100 {
+		w.Bool(true)
+		w.Int64(-3)
+		w.String("hi")
+	}
+	w.Flush()
+
+	var b strings.Builder
+	_ = pw.DumpTo(&b)
+	pr := pkgbits.NewPkgDecoder("package_id", b.String())
+
+	// Without a trace, reading values shouldn't allocate; only the
+	// reference table does.
+	allocs := testing.AllocsPerRun(10, func() {
+		r := pr.NewDecoder(pkgbits.SectionMeta, pkgbits.PublicRootIdx, pkgbits.SyncPublic)
+		for range 100 {
+			r.Bool()
+			r.Int64()
+			_ = r.String()
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("decoding 100 values of each kind takes %v allocations, want at most 1", allocs)
+	}
+}
+
 // Type checker to enforce that know V* have the constant values they must have.
 var _ [0]bool = [pkgbits.V0]bool{}
 var _ [1]bool = [pkgbits.V1]bool{}
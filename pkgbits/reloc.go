@@ -4,6 +4,8 @@
 
 package pkgbits
 
+import "fmt"
+
 // A SectionKind indicates a section, as well as the ordering of sections within
 // unified export data. Any object given a dedicated section can be referred to
 // via a section / index pair (and thus dereferenced) in other sections.
@@ -24,6 +26,26 @@ const (
 	numRelocs = iota
 )
 
+var sectionNames = [numRelocs]string{
+	"SectionString",
+	"SectionMeta",
+	"SectionPosBase",
+	"SectionPkg",
+	"SectionName",
+	"SectionType",
+	"SectionObj",
+	"SectionObjExt",
+	"SectionObjDict",
+	"SectionBody",
+}
+
+func (k SectionKind) String() string {
+	if 0 <= k && k < numRelocs {
+		return sectionNames[k]
+	}
+	return fmt.Sprintf("SectionKind(%d)", int32(k))
+}
+
 // An Index represents a bitstream element index *within* (i.e., relative to) a
 // particular section.
 type Index int32
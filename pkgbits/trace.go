@@ -0,0 +1,82 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkgbits
+
+import "fmt"
+
+// An ElemTrace records the values that one Decoder read from an
+// element's bitstream, in the order read. See PkgDecoder.TraceElem.
+type ElemTrace struct {
+	Section SectionKind
+	Index   RelElemIdx
+
+	// RelocsEnd is the byte offset where the element's reference
+	// table ends and its own data begins.
+	RelocsEnd int
+
+	Entries []TraceEntry
+}
+
+// A TraceEntry describes a value read from an element bitstream.
+type TraceEntry struct {
+	Start, End int // byte range within the element's data
+
+	// Kind is what was read: "sync" for a sync marker, "uvarint",
+	// "zvarint" (a zig-zag encoded varint) or "bool".
+	Kind string
+
+	// Value is the value decoded, such as "12", "true" or "Object".
+	Value string
+
+	// Notes describe what the value meant to the Decoder: the
+	// reference table entry it is part of, the element a reference
+	// resolves to, or the string that a string reference denotes.
+	Notes []string
+}
+
+// TraceElem makes the Decoders that pr creates for element (k, idx)
+// from now on record the values they read. Each such Decoder appends
+// its own ElemTrace to *traces, so an element that is decoded more than
+// once, or only partly by a peek, has several.
+func (pr *PkgDecoder) TraceElem(k SectionKind, idx RelElemIdx, traces *[]*ElemTrace) {
+	pr.traceKind, pr.traceIdx, pr.traces = k, idx, traces
+}
+
+// newTrace returns the trace that a new Decoder for element (k, idx)
+// should record to, if any.
+func (pr *PkgDecoder) newTrace(k SectionKind, idx RelElemIdx) *ElemTrace {
+	if pr.traces == nil || k != pr.traceKind || idx != pr.traceIdx {
+		return nil
+	}
+	t := &ElemTrace{Section: k, Index: idx}
+	*pr.traces = append(*pr.traces, t)
+	return t
+}
+
+// pos returns r's current byte offset within the element's data.
+func (r *Decoder) pos() int {
+	return int(r.Data.Size()) - r.Data.Len()
+}
+
+// traceRead records that the bytes from start to the current position
+// held a value of the given kind, if r is tracing. Callers check
+// r.trace first, so that decoding without a trace doesn't format values
+// only to throw them away.
+func (r *Decoder) traceRead(start int, kind, value string) {
+	if r.trace == nil {
+		return
+	}
+	r.trace.Entries = append(r.trace.Entries, TraceEntry{Start: start, End: r.pos(), Kind: kind, Value: value})
+}
+
+// traceNote adds a note to the value most recently read, if r is
+// tracing.
+func (r *Decoder) traceNote(format string, args ...any) {
+	if r.trace == nil || len(r.trace.Entries) == 0 {
+		return
+	}
+	e := &r.trace.Entries[len(r.trace.Entries)-1]
+	e.Notes = append(e.Notes, fmt.Sprintf(format, args...))
+}
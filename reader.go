@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// A pkgReader holds the shared state for decoding a package's export
+// data. Decoded elements are cached by their section-relative index.
+type pkgReader struct {
+	pkgbits.PkgDecoder
+
+	// selfIdx is the SectionPkg index of the package being described,
+	// as referenced by the public root.
+	selfIdx pkgbits.Index
+
+	// selfPath is the import path of the package being described, if
+	// known, and selfPathSource names where it was found. See
+	// exportFile.selfPath.
+	selfPath       string
+	selfPathSource string
+
+	// hasInit is the public root's legacy flag reporting whether the
+	// package has init functions. It is only encoded before V2.
+	hasInit bool
+
+	// exports holds the SectionObj indices listed by the public root,
+	// in the order importers read them. After linking, this is every
+	// object in the export data, including those of other packages.
+	exports []pkgbits.Index
+
+	// goarch and newInliner describe the build configuration that
+	// produced the export data, which affects how ObjExt elements are
+	// encoded. They default to a non-wasm target without
+	// GOEXPERIMENT=newinliner.
+	goarch     string
+	newInliner bool
+
+	// bodies maps the linker symbol names listed in the private root
+	// to their SectionBody elements.
+	bodies map[string]pkgbits.Index
+
+	// decoded, if non-nil, records every element reader created, so
+	// that validation can check each element was read to its end.
+	decoded []decodedElem
+
+	posBases []*posBase
+	pkgs     []*pkgInfo
+	typs     []*typeNode
+	objs     []*objNode
+}
+
+// A pkgInfo is a decoded SectionPkg element.
+type pkgInfo struct {
+	path    string
+	name    string
+	imports []pkgbits.Index // SectionPkg indices of the package's direct imports
+}
+
+// newPkgReader returns a pkgReader for the given package decoder.
+func newPkgReader(pd pkgbits.PkgDecoder) *pkgReader {
+	pr := &pkgReader{
+		PkgDecoder: pd,
+		posBases:   make([]*posBase, pd.NumElems(pkgbits.SectionPosBase)),
+		pkgs:       make([]*pkgInfo, pd.NumElems(pkgbits.SectionPkg)),
+		typs:       make([]*typeNode, pd.NumElems(pkgbits.SectionType)),
+		objs:       make([]*objNode, pd.NumElems(pkgbits.SectionObj)),
+	}
+
+	pr.readPublicRoot()
+	pr.readPrivateRoot()
+	return pr
+}
+
+// readPublicRoot reads the public root, which describes the package
+// for importers.
+func (pr *pkgReader) readPublicRoot() {
+	r := pr.newReader(pkgbits.SectionMeta, pkgbits.PublicRootIdx, pkgbits.SyncPublic)
+	pr.selfIdx = r.pkg()
+	if r.Version().Has(pkgbits.HasInit) {
+		pr.hasInit = r.Bool()
+	}
+	pr.exports = make([]pkgbits.Index, r.Len())
+	for i := range pr.exports {
+		pr.exports[i] = r.objInfo().idx
+	}
+	r.Sync(pkgbits.SyncEOF)
+}
+
+// readPrivateRoot reads the private root, which lists the function
+// bodies available for inlining.
+func (pr *pkgReader) readPrivateRoot() {
+	r := pr.newReader(pkgbits.SectionMeta, pkgbits.PrivateRootIdx, pkgbits.SyncPrivate)
+	r.Bool() // has .inittask
+	pr.bodies = make(map[string]pkgbits.Index)
+	for i, n := 0, r.Len(); i < n; i++ {
+		path := r.String()
+		name := r.String()
+		pr.bodies[path+"."+name] = r.Reloc(pkgbits.SectionBody)
+	}
+	r.Sync(pkgbits.SyncEOF)
+}
+
+// decodeError returns the error that decoding panicked with, such as a
+// *pkgbits.DesyncError, as an error.
+func decodeError(r any) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
+// A reader holds the state for decoding a single element.
+type reader struct {
+	pkgbits.Decoder
+
+	p *pkgReader
+
+	// dict is the dictionary of the object being decoded, if any.
+	dict *readerDict
+}
+
+func (pr *pkgReader) newReader(k pkgbits.SectionKind, idx pkgbits.Index, marker pkgbits.SyncMarker) *reader {
+	r := &reader{
+		Decoder: pr.NewDecoder(k, idx, marker),
+		p:       pr,
+	}
+	if pr.decoded != nil {
+		pr.decoded = append(pr.decoded, decodedElem{k, idx, r})
+	}
+	return r
+}
+
+// @@@ Positions
+
+// A posBase is a decoded SectionPosBase element: either a source file,
+// or a //line directive that renames and renumbers the lines that
+// follow it.
+type posBase struct {
+	filename string
+	fileBase bool
+
+	// For line directives: the position of the directive itself, and
+	// the line and column it assigns to the position following it. A
+	// column of zero means that columns are unknown.
+	pos       srcPos
+	line, col uint
+}
+
+// A srcPos is a decoded source position. The zero value is an unknown
+// position.
+type srcPos struct {
+	base      *posBase
+	line, col uint
+}
+
+// String returns the position as "file:line:col", as adjusted by any
+// line directive. Unknown lines and columns are omitted.
+func (p srcPos) String() string {
+	if p.base == nil {
+		return "?"
+	}
+
+	b := p.base
+	line, col := p.line, p.col
+	switch {
+	case b.fileBase:
+	case b.line == 0:
+		// A directive with line 0 leaves lines unknown.
+		line, col = 0, 0
+	default:
+		// As in cmd/internal/src: lines count on from the directive,
+		// and columns only shift on the directive's own line.
+		line = b.line + (p.line - b.pos.line)
+		switch {
+		case b.col == 0:
+			col = 0
+		case p.line == b.pos.line:
+			col = b.col + (p.col - b.pos.col)
+		}
+	}
+
+	s := b.filename
+	if line != 0 {
+		s += ":" + strconv.FormatUint(uint64(line), 10)
+		if col != 0 {
+			s += ":" + strconv.FormatUint(uint64(col), 10)
+		}
+	}
+	return s
+}
+
+// pos reads a position from the bitstream.
+func (r *reader) pos() srcPos {
+	r.Sync(pkgbits.SyncPos)
+	if !r.Bool() {
+		return srcPos{}
+	}
+	return srcPos{
+		base: r.p.posBaseIdx(r.Reloc(pkgbits.SectionPosBase)),
+		line: r.Uint(),
+		col:  r.Uint(),
+	}
+}
+
+// posBaseIdx returns the specified position base, reading it first if
+// needed.
+func (pr *pkgReader) posBaseIdx(idx pkgbits.Index) *posBase {
+	if b := pr.posBases[idx]; b != nil {
+		return b
+	}
+
+	r := pr.newReader(pkgbits.SectionPosBase, idx, pkgbits.SyncPosBase)
+	b := &posBase{filename: r.String(), fileBase: r.Bool()}
+	if !b.fileBase {
+		b.pos = r.pos()
+		b.line = r.Uint()
+		b.col = r.Uint()
+	}
+
+	pr.posBases[idx] = b
+	return b
+}
+
+// @@@ Packages
+
+// pkg reads a package reference from the bitstream.
+func (r *reader) pkg() pkgbits.Index {
+	r.Sync(pkgbits.SyncPkg)
+	return r.Reloc(pkgbits.SectionPkg)
+}
+
+// pkgIdx returns the specified package, reading it first if needed.
+func (pr *pkgReader) pkgIdx(idx pkgbits.Index) *pkgInfo {
+	if pkg := pr.pkgs[idx]; pkg != nil {
+		return pkg
+	}
+
+	r := pr.newReader(pkgbits.SectionPkg, idx, pkgbits.SyncPkgDef)
+	pkg := &pkgInfo{path: r.String()}
+	if pkg.path == "" {
+		// The package itself, whose path older toolchains omit.
+		pkg.path = pr.selfPath
+	}
+	if pkg.path != "builtin" && pkg.path != "unsafe" {
+		pkg.name = r.String()
+		pkg.imports = make([]pkgbits.Index, r.Len())
+		for i := range pkg.imports {
+			pkg.imports[i] = r.pkg()
+		}
+	} else {
+		pkg.name = pkg.path
+	}
+	pr.pkgs[idx] = pkg
+	return pkg
+}
+
+// qualifier returns the prefix used when naming objects declared in
+// the specified package: empty for the package itself and for
+// predeclared objects, and "name." otherwise.
+func (pr *pkgReader) qualifier(idx pkgbits.Index) string {
+	if idx == pr.selfIdx {
+		return ""
+	}
+	pkg := pr.pkgIdx(idx)
+	if pkg.path == "builtin" {
+		return ""
+	}
+	return pkg.name + "."
+}
+
+// @@@ Objects
+
+// objName returns the qualified name of the specified object.
+func (pr *pkgReader) objName(idx pkgbits.Index) string {
+	r := pr.newReader(pkgbits.SectionName, idx, pkgbits.SyncObject1)
+	pkg, name := r.qualifiedIdent()
+	return pr.qualifier(pkg) + name
+}
+
+func (r *reader) qualifiedIdent() (pkgbits.Index, string) { return r.ident(pkgbits.SyncSym) }
+func (r *reader) localIdent() (pkgbits.Index, string)     { return r.ident(pkgbits.SyncLocalIdent) }
+func (r *reader) selector() (pkgbits.Index, string)       { return r.ident(pkgbits.SyncSelector) }
+
+func (r *reader) ident(marker pkgbits.SyncMarker) (pkgbits.Index, string) {
+	r.Sync(marker)
+	return r.pkg(), r.String()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"go/types"
+	"slices"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+func TestLineDirectives(t *testing.T) {
+	w := uirtest.New(pkgbits.V2, 0, testPath, "example")
+	file := w.File("parser.go")
+
+	// A //line gen.y:100:5 comment on line 10, a //line gen.y:7
+	// comment on line 20, which leaves columns unknown, and a
+	// //line gen.y:0 comment on line 30, which leaves lines unknown.
+	withCol := w.LineBase(file.Pos(11, 1), "gen.y", 100, 5)
+	withoutCol := w.LineBase(file.Pos(21, 1), "gen.y", 7, 0)
+	withoutLine := w.LineBase(file.Pos(31, 1), "gen.y", 0, 0)
+
+	tests := []struct {
+		pos  uirtest.Pos
+		want string
+	}{
+		{file.Pos(3, 9), "parser.go:3:9"},
+		{file.Pos(3, 0), "parser.go:3"},
+		{uirtest.Pos{}, "?"},
+		{withCol.Pos(11, 3), "gen.y:100:7"},
+		{withCol.Pos(12, 3), "gen.y:101:3"},
+		{withoutCol.Pos(21, 4), "gen.y:7"},
+		{withoutCol.Pos(25, 4), "gen.y:11"},
+		{withoutLine.Pos(31, 4), "gen.y"},
+		{withoutLine.Pos(35, 4), "gen.y"},
+	}
+	elems := make([]pkgbits.Index, len(tests))
+	for i, test := range tests {
+		e := w.NewBody()
+		e.Pos(test.pos)
+		elems[i] = e.Flush()
+	}
+
+	pr := testReader(t, w.Bytes())
+	for i, test := range tests {
+		r := pr.newReader(pkgbits.SectionBody, elems[i], pkgbits.SyncFuncBody)
+		if got := r.pos().String(); got != test.want {
+			t.Errorf("position %d is %s, want %s", i, got, test.want)
+		}
+	}
+}
+
+func TestPublicRoot(t *testing.T) {
+	for _, version := range []pkgbits.Version{pkgbits.V1, pkgbits.V2} {
+		w := uirtest.New(version, -1, testPath, "example")
+		w.HasInit = true
+		var objs []*uirtest.Object
+		for _, name := range []string{"b", "a", "c"} {
+			obj := w.NewObject(w.Self, name, pkgbits.ObjVar)
+			obj.Type = w.Basic(types.Int)
+			obj.Flush()
+			objs = append(objs, obj)
+		}
+		// Importers read the exports in the order listed, which need not
+		// be the order of the elements.
+		for _, i := range []int{2, 0, 1} {
+			w.Export(objs[i])
+		}
+
+		pr := testReader(t, w.Bytes())
+		want := []pkgbits.Index{objs[2].Idx, objs[0].Idx, objs[1].Idx}
+		if !slices.Equal(pr.exports, want) {
+			t.Errorf("V%d: public root lists %v, want %v", version, pr.exports, want)
+		}
+		if pr.hasInit != (version == pkgbits.V1) {
+			t.Errorf("V%d: hasInit is %v", version, pr.hasInit)
+		}
+		if got := pr.pkgIdx(pr.selfIdx).path; got != testPath {
+			t.Errorf("V%d: public root refers to package %q, want %q", version, got, testPath)
+		}
+	}
+}
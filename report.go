@@ -0,0 +1,905 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// A report is everything decoded from a file's export data, as shown
+// by each output format. Elements are referred to as "section:index",
+// and refs list the elements in an element's reference table. It is
+// also the JSON schema of -format=json, documented in the README:
+// fields may be added, but existing ones keep their names and meaning
+// while schemaVersion stays the same.
+//
+// Lists are in index order, and are cut short by -limit; the section
+// statistics and counts give their full lengths. Elements that cannot
+// be decoded have Error set instead of their other fields.
+type report struct {
+	SchemaVersion int `json:"schemaVersion"`
+
+	Build       buildReport       `json:"build"`
+	Format      formatReport      `json:"format"`
+	Sections    []sectionReport   `json:"sections"`
+	Strings     []stringReport    `json:"strings"`
+	PosBases    []posBaseReport   `json:"posBases"`
+	Packages    []packageReport   `json:"packages"`
+	Types       []typeReport      `json:"types"`
+	ObjectKinds []objKindReport   `json:"objectKinds"`
+	Objects     []objectReport    `json:"objects"`
+	PublicRoot  publicRootReport  `json:"publicRoot"`
+	PrivateRoot privateRootReport `json:"privateRoot"`
+
+	GenericBodies genericBodiesReport `json:"genericBodies"`
+}
+
+// reportSchemaVersion is the current report.SchemaVersion.
+const reportSchemaVersion = 1
+
+type buildReport struct {
+	ImportPath       string `json:"importPath,omitempty"`
+	ImportPathSource string `json:"importPathSource,omitempty"` // "public root", "object header", "importcfg" or "go list"
+	File             string `json:"file"`
+	FoundBy          string `json:"foundBy,omitempty"` // "importcfg" or "go list" if named by import path
+	Container        string `json:"container"`
+
+	// Omitted for raw Unified IR, which has no object header.
+	Header *headerReport `json:"header,omitempty"`
+}
+
+type headerReport struct {
+	GOOS        string   `json:"goos"`
+	GOARCH      string   `json:"goarch"`
+	ArchVariant string   `json:"archVariant,omitempty"` // such as "GOAMD64=v1"
+	Toolchain   string   `json:"toolchain"`
+	Experiments []string `json:"experiments"`
+	BuildID     string   `json:"buildID,omitempty"`
+	Main        bool     `json:"main"`
+	Other       []string `json:"other,omitempty"`
+}
+
+type formatReport struct {
+	Version             int    `json:"version"`
+	SyncMarkers         bool   `json:"syncMarkers"`
+	TotalElements       int    `json:"totalElements"`
+	Fingerprint         string `json:"fingerprint"`         // as stored, in hex
+	ComputedFingerprint string `json:"computedFingerprint"` // hash of the data, in hex
+	FingerprintVerified bool   `json:"fingerprintVerified"`
+}
+
+type sectionReport struct {
+	Name     string `json:"name"`
+	Elements int    `json:"elements"`
+}
+
+type stringReport struct {
+	Index int    `json:"index"`
+	Value string `json:"value"`
+}
+
+type posBaseReport struct {
+	Index    int    `json:"index"`
+	Filename string `json:"filename"`
+	FileBase bool   `json:"fileBase"`
+
+	// For line bases, from //line directives: the position of the
+	// directive and the line and column it starts at. A column of zero
+	// means columns are unknown.
+	Pos  string `json:"pos,omitempty"`
+	Line uint   `json:"line,omitempty"`
+	Col  uint   `json:"col,omitempty"`
+
+	Refs []string `json:"refs,omitempty"`
+}
+
+type packageReport struct {
+	Index   int      `json:"index"`
+	Path    string   `json:"path,omitempty"` // "" for the package itself if its path is unknown
+	Name    string   `json:"name,omitempty"`
+	Self    bool     `json:"self,omitempty"`
+	Archive string   `json:"archive,omitempty"` // from -importcfg
+	Refs    []string `json:"refs,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+type typeReport struct {
+	Index int      `json:"index"`
+	Code  string   `json:"code,omitempty"`
+	Type  string   `json:"type,omitempty"`
+	Refs  []string `json:"refs,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+type objKindReport struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type objectReport struct {
+	Index int    `json:"index"`
+	Tag   string `json:"tag,omitempty"`
+	Decl  string `json:"decl,omitempty"`
+	Pos   string `json:"pos,omitempty"`
+
+	// Dict and Ext describe the dictionary and extension data as text
+	// for the text and HTML formats; JSON has DictInfo and ExtInfo.
+	Dict     []string       `json:"-"` // generic objects only
+	DictInfo *dictReport    `json:"dictInfo,omitempty"`
+	Ext      string         `json:"-"`
+	ExtInfo  *extReport     `json:"extInfo,omitempty"`
+	Methods  []methodReport `json:"methods,omitempty"`
+	Refs     []string       `json:"refs,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+type methodReport struct {
+	Decl    string     `json:"decl"`
+	Pos     string     `json:"pos"`
+	Ext     string     `json:"-"` // as text, for the text and HTML formats
+	ExtInfo *extReport `json:"extInfo,omitempty"`
+}
+
+// A dictReport is the dictionary of a generic object. Types are shown
+// as Go source.
+type dictReport struct {
+	TypeParams  []tparamReport     `json:"typeParams"`
+	Derived     []derivedReport    `json:"derived,omitempty"`
+	MethodExprs []methodExprReport `json:"methodExprs,omitempty"`
+	Subdicts    []subdictReport    `json:"subdicts,omitempty"`
+	RTypes      []string           `json:"rtypes,omitempty"`
+	Itabs       []itabReport       `json:"itabs,omitempty"`
+}
+
+type tparamReport struct {
+	Name     string `json:"name"`
+	Bound    string `json:"bound,omitempty"` // explicit type parameters only
+	Implicit bool   `json:"implicit,omitempty"`
+	Basic    bool   `json:"basic,omitempty"`
+}
+
+type derivedReport struct {
+	TypeIndex int    `json:"typeIndex"`
+	Type      string `json:"type"`
+}
+
+type methodExprReport struct {
+	TypeParam string `json:"typeParam"`
+	Method    string `json:"method"`
+}
+
+type subdictReport struct {
+	ObjectIndex int    `json:"objectIndex"`
+	Object      string `json:"object"`
+}
+
+type itabReport struct {
+	Type  string `json:"type"`
+	Iface string `json:"iface"`
+}
+
+// An extReport is the extension data of an object or method. Which
+// fields are set depends on the object's tag, as for objExt.
+type extReport struct {
+	Pragmas []string    `json:"pragmas,omitempty"` // such as "//go:noinline"
+	Link    *linkReport `json:"link,omitempty"`    // functions, methods and variables
+
+	// ObjType.
+	TypeSym *int64 `json:"typeSym,omitempty"` // -1 if none
+	PtrSym  *int64 `json:"ptrSym,omitempty"`
+
+	// Functions and methods.
+	WasmImport []string       `json:"wasmImport,omitempty"` // module, name
+	WasmExport string         `json:"wasmExport,omitempty"`
+	Generic    bool           `json:"generic,omitempty"` // compiled by importers, without analysis results
+	BodyIndex  *int           `json:"bodyIndex,omitempty"`
+	ABI        string         `json:"abi,omitempty"`
+	Inline     *inlineReport  `json:"inline,omitempty"` // nil if not inlinable
+	Escapes    []escapeReport `json:"escapes,omitempty"`
+}
+
+type linkReport struct {
+	SymIdx   int64  `json:"symIdx"` // -1 if not indexed
+	Linkname string `json:"linkname,omitempty"`
+	Std      bool   `json:"std,omitempty"`
+}
+
+type inlineReport struct {
+	Cost            int    `json:"cost"`
+	CanDelayResults bool   `json:"canDelayResults,omitempty"`
+	Properties      string `json:"properties,omitempty"` // only with GOEXPERIMENT=newinliner
+}
+
+// An escapeReport is the escape analysis note of a receiver or
+// parameter.
+type escapeReport struct {
+	Param string `json:"param"` // "#i" if unnamed
+	Tag   string `json:"tag"`   // as encoded by the compiler
+	Desc  string `json:"desc"`
+}
+
+type publicRootReport struct {
+	Path        string         `json:"path,omitempty"`
+	Name        string         `json:"name"`
+	HasInit     *bool          `json:"hasInit,omitempty"` // before V2 only
+	ExportCount int            `json:"exportCount"`
+	Exports     []exportReport `json:"exports"`
+}
+
+type exportReport struct {
+	Index       int    `json:"index"`
+	ObjectIndex int    `json:"objectIndex"`
+	Tag         string `json:"tag,omitempty"`
+	Decl        string `json:"decl,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+type privateRootReport struct {
+	HasInittask bool         `json:"hasInittask"`
+	BodyCount   int          `json:"bodyCount"`
+	Bodies      []bodyReport `json:"bodies"`
+}
+
+// genericBodiesReport lists the bodies of generic functions and
+// methods, which the extension data of their objects refers to.
+type genericBodiesReport struct {
+	BodyCount int          `json:"bodyCount"`
+	Bodies    []bodyReport `json:"bodies"`
+}
+
+// errNoDecl is the bodyReport.Error of bodies whose function isn't
+// among the objects.
+const errNoDecl = "declaration not found"
+
+type bodyReport struct {
+	Index     int      `json:"index"`
+	Package   string   `json:"package"`
+	Symbol    string   `json:"symbol"`
+	BodyIndex int      `json:"bodyIndex"`
+	Source    string   `json:"source,omitempty"` // as importers will inline it
+	Refs      []string `json:"refs,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// objTags lists the object tags in the order reports count them.
+var objTags = []pkgbits.CodeObj{
+	pkgbits.ObjAlias,
+	pkgbits.ObjConst,
+	pkgbits.ObjType,
+	pkgbits.ObjFunc,
+	pkgbits.ObjVar,
+	pkgbits.ObjStub,
+}
+
+// newReport decodes the file's export data into a report, showing at
+// most limit entries per list if limit is positive. cfg, if non-nil,
+// locates the archives of referenced packages.
+func newReport(f *exportFile, cfg *importCfg, limit int) (rep *report, err error) {
+	// Decoding failures outside the per-element recovers below abort
+	// decoding.
+	defer func() {
+		if r := recover(); r != nil {
+			rep, err = nil, decodeError(r)
+		}
+	}()
+
+	decoder, err := f.newDecoder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	pr := f.newPkgReader(decoder, cfg)
+
+	shown := func(n int) int {
+		if limit > 0 && limit < n {
+			return limit
+		}
+		return n
+	}
+
+	rep = &report{SchemaVersion: reportSchemaVersion}
+
+	// Build metadata
+	rep.Build = buildReport{
+		ImportPath:       pr.selfPath,
+		ImportPathSource: pr.selfPathSource,
+		File:             f.filename,
+		FoundBy:          f.pathSource,
+		Container:        f.container.String(),
+	}
+	if h := f.header; h != nil {
+		rep.Build.Header = &headerReport{
+			GOOS:        h.GOOS,
+			GOARCH:      h.GOARCH,
+			ArchVariant: h.ArchVariant,
+			Toolchain:   h.Version,
+			Experiments: append([]string{}, h.Experiments...),
+			BuildID:     h.BuildID,
+			Main:        h.Main,
+			Other:       h.Extra,
+		}
+	}
+
+	// Format metadata
+	fp, computed := decoder.Fingerprint(), decoder.ComputeFingerprint()
+	rep.Format = formatReport{
+		Version:             int(decoder.Version()),
+		SyncMarkers:         decoder.SyncMarkers(),
+		TotalElements:       decoder.TotalElems(),
+		Fingerprint:         hex.EncodeToString(fp[:]),
+		ComputedFingerprint: hex.EncodeToString(computed[:]),
+		FingerprintVerified: fp == computed,
+	}
+
+	for k := pkgbits.SectionString; k <= pkgbits.SectionBody; k++ {
+		rep.Sections = append(rep.Sections, sectionReport{Name: k.String(), Elements: decoder.NumElems(k)})
+	}
+
+	// Strings
+	rep.Strings = []stringReport{}
+	for i := range shown(decoder.NumElems(pkgbits.SectionString)) {
+		rep.Strings = append(rep.Strings, stringReport{Index: i, Value: decoder.StringIdx(pkgbits.Index(i))})
+	}
+
+	// Position bases
+	rep.PosBases = []posBaseReport{}
+	for i := range shown(decoder.NumElems(pkgbits.SectionPosBase)) {
+		b := pr.posBaseIdx(pkgbits.Index(i))
+		rb := posBaseReport{Index: i, Filename: b.filename, FileBase: b.fileBase, Refs: refStrings(decoder, pkgbits.SectionPosBase, i)}
+		if !b.fileBase {
+			rb.Pos, rb.Line, rb.Col = b.pos.String(), b.line, b.col
+		}
+		rep.PosBases = append(rep.PosBases, rb)
+	}
+
+	// Packages
+	rep.Packages = []packageReport{}
+	for i := range shown(decoder.NumElems(pkgbits.SectionPkg)) {
+		rp := packageReport{Index: i}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					rp = packageReport{Index: i, Error: fmt.Sprint(r)}
+				}
+			}()
+			idx := pkgbits.Index(i)
+			pkg := pr.pkgIdx(idx)
+			rp.Path, rp.Name, rp.Self = pkg.path, pkg.name, idx == pr.selfIdx
+			if file, ok := cfg.lookup(rp.Path); ok && !rp.Self {
+				rp.Archive = file
+			}
+			rp.Refs = refStrings(decoder, pkgbits.SectionPkg, i)
+		}()
+		rep.Packages = append(rep.Packages, rp)
+	}
+
+	// Types
+	rep.Types = []typeReport{}
+	for i := range shown(decoder.NumElems(pkgbits.SectionType)) {
+		rt := typeReport{Index: i}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					rt = typeReport{Index: i, Error: fmt.Sprint(r)}
+				}
+			}()
+			info := typeInfo{idx: pkgbits.Index(i)}
+			typ := pr.typIdx(info, nil)
+			rt.Code = typeCodeName(typ.code)
+			rt.Type = pr.typeString(info, nil)
+			rt.Refs = refStrings(decoder, pkgbits.SectionType, i)
+		}()
+		rep.Types = append(rep.Types, rt)
+	}
+
+	// Objects
+	objCount := decoder.NumElems(pkgbits.SectionObj)
+	counts := make(map[pkgbits.CodeObj]int)
+	for i := range objCount {
+		_, _, tag := decoder.PeekObj(pkgbits.Index(i))
+		counts[tag]++
+	}
+	rep.ObjectKinds = []objKindReport{}
+	for _, tag := range objTags {
+		if counts[tag] > 0 {
+			rep.ObjectKinds = append(rep.ObjectKinds, objKindReport{Tag: objTagName(tag), Count: counts[tag]})
+		}
+	}
+	rep.Objects = []objectReport{}
+	for i := range shown(objCount) {
+		rep.Objects = append(rep.Objects, pr.objectReport(pkgbits.Index(i)))
+	}
+
+	// Public root
+	self := pr.pkgIdx(pr.selfIdx)
+	rep.PublicRoot = publicRootReport{
+		Path:        self.path,
+		Name:        self.name,
+		ExportCount: len(pr.exports),
+		Exports:     []exportReport{},
+	}
+	if decoder.Version().Has(pkgbits.HasInit) {
+		rep.PublicRoot.HasInit = &pr.hasInit
+	}
+	for i, idx := range pr.exports[:shown(len(pr.exports))] {
+		re := exportReport{Index: i, ObjectIndex: int(idx)}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					re.Error = fmt.Sprint(r)
+				}
+			}()
+			obj := pr.objIdx(idx)
+			re.Tag = objTagName(obj.tag)
+			re.Decl = pr.declString(obj)
+		}()
+		rep.PublicRoot.Exports = append(rep.PublicRoot.Exports, re)
+	}
+
+	// Private root
+	r := decoder.NewDecoder(pkgbits.SectionMeta, pkgbits.PrivateRootIdx, pkgbits.SyncPrivate)
+	rep.PrivateRoot.HasInittask = r.Bool()
+	rep.PrivateRoot.BodyCount = r.Len()
+	rep.PrivateRoot.Bodies = []bodyReport{}
+	maxShow := shown(rep.PrivateRoot.BodyCount)
+	decls := pr.funcDecls()
+	for i := range rep.PrivateRoot.BodyCount {
+		rb := bodyReport{Index: i, Package: r.String(), Symbol: r.String()}
+		bodyIdx := r.Reloc(pkgbits.SectionBody)
+		rb.BodyIndex = int(bodyIdx)
+		if i >= maxShow {
+			continue
+		}
+		rb.Refs = refStrings(decoder, pkgbits.SectionBody, rb.BodyIndex)
+
+		// Show the body as importers will inline it.
+		if decl, ok := decls[rb.Package+"."+rb.Symbol]; !ok {
+			rb.Error = errNoDecl
+		} else {
+			pr.bodySource(&rb, bodyIdx, decl)
+		}
+		rep.PrivateRoot.Bodies = append(rep.PrivateRoot.Bodies, rb)
+	}
+	r.Sync(pkgbits.SyncEOF)
+
+	// Generic function bodies, which only extension data refers to
+	generic := pr.genericBodies()
+	rep.GenericBodies.BodyCount = len(generic)
+	rep.GenericBodies.Bodies = []bodyReport{}
+	for i, g := range generic[:shown(len(generic))] {
+		path := pr.pkgIdx(g.decl.obj.pkg).path
+		rb := bodyReport{Index: i, Package: path, Symbol: strings.TrimPrefix(g.sym, path+"."), BodyIndex: int(g.idx)}
+		rb.Refs = refStrings(decoder, pkgbits.SectionBody, rb.BodyIndex)
+		pr.bodySource(&rb, g.idx, g.decl)
+		rep.GenericBodies.Bodies = append(rep.GenericBodies.Bodies, rb)
+	}
+
+	return rep, nil
+}
+
+// bodySource sets rb.Source to body idx of decl, as importers will
+// inline it, or rb.Error if it cannot be decoded.
+func (pr *pkgReader) bodySource(rb *bodyReport, idx pkgbits.Index, decl funcDecl) {
+	defer func() {
+		if r := recover(); r != nil {
+			rb.Error = fmt.Sprint(r)
+		}
+	}()
+	rb.Source = pr.bodyString(idx, decl)
+}
+
+// objectReport decodes the specified object and its extension data.
+func (pr *pkgReader) objectReport(idx pkgbits.Index) (ro objectReport) {
+	defer func() {
+		if r := recover(); r != nil {
+			ro = objectReport{Index: int(idx), Error: fmt.Sprint(r)}
+		}
+	}()
+
+	obj := pr.objIdx(idx)
+	ro = objectReport{Index: int(idx), Tag: objTagName(obj.tag), Decl: pr.declString(obj)}
+	if obj.tag == pkgbits.ObjStub {
+		return ro
+	}
+	ro.Pos = obj.pos.String()
+	ro.Refs = refStrings(&pr.PkgDecoder, pkgbits.SectionObj, int(idx))
+	if obj.dict.generic() {
+		ro.Dict = pr.dictLines(obj.dict)
+		ro.DictInfo = pr.dictReport(obj.dict)
+	}
+
+	ext := pr.objExtIdx(idx, obj)
+	switch obj.tag {
+	case pkgbits.ObjFunc:
+		sym, names := pr.funcSymName(obj, nil), paramNames(nil, obj.sig)
+		ro.Ext = pr.funcExtString(ext.funcs[0], sym, names)
+		ro.ExtInfo = pr.funcExtReport(ext.funcs[0], sym, names)
+	case pkgbits.ObjType:
+		ro.Ext = fmt.Sprintf("type symbols %d, %d", ext.typeSym, ext.ptrSym)
+		if ext.pragma != 0 {
+			ro.Ext += fmt.Sprintf(", %s", ext.pragma)
+		}
+		ro.ExtInfo = &extReport{Pragmas: ext.pragma.names(), TypeSym: &ext.typeSym, PtrSym: &ext.ptrSym}
+	case pkgbits.ObjVar:
+		ro.Ext = ext.link.String()
+		ro.ExtInfo = &extReport{Link: newLinkReport(ext.link)}
+	}
+	for j, m := range obj.methods {
+		rm := methodReport{Decl: pr.methodString(obj, m), Pos: m.pos.String()}
+		if j < len(ext.funcs) {
+			sym, names := pr.funcSymName(obj, m), paramNames(&m.recv, m.sig)
+			rm.Ext = pr.funcExtString(ext.funcs[j], sym, names)
+			rm.ExtInfo = pr.funcExtReport(ext.funcs[j], sym, names)
+		}
+		ro.Methods = append(ro.Methods, rm)
+	}
+	return ro
+}
+
+// funcExtReport is the structured form of funcExtString.
+func (pr *pkgReader) funcExtReport(ext *funcExt, sym string, names []string) *extReport {
+	re := &extReport{
+		Pragmas:    ext.pragma.names(),
+		Link:       newLinkReport(ext.link),
+		WasmExport: ext.wasmExport,
+	}
+	if ext.wasmImport[0] != "" || ext.wasmImport[1] != "" {
+		re.WasmImport = ext.wasmImport[:]
+	}
+	if !ext.extended {
+		body := int(ext.body)
+		re.Generic, re.BodyIndex = true, &body
+		return re
+	}
+
+	re.ABI = abiName(ext.abi)
+	if ext.inl != nil {
+		re.Inline = &inlineReport{Cost: ext.inl.cost, CanDelayResults: ext.inl.canDelayResults, Properties: ext.inl.properties}
+	}
+	if idx, ok := pr.bodies[sym]; ok {
+		body := int(idx)
+		re.BodyIndex = &body
+	}
+	for i, note := range ext.escapes {
+		name := fmt.Sprintf("#%d", i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		re.Escapes = append(re.Escapes, escapeReport{Param: name, Tag: note, Desc: escapeString(note)})
+	}
+	return re
+}
+
+func newLinkReport(l linkInfo) *linkReport {
+	return &linkReport{SymIdx: l.symIdx, Linkname: l.linkname, Std: l.std}
+}
+
+// dictReport is the structured form of dictLines.
+func (pr *pkgReader) dictReport(dict *readerDict) *dictReport {
+	rd := &dictReport{TypeParams: []tparamReport{}}
+	for i := range dict.tparams {
+		tp := tparamReport{Name: dict.tparamName(i), Implicit: i < dict.implicits, Basic: dict.basic[i]}
+		if !tp.Implicit {
+			tp.Bound = pr.typeString(dict.bounds[i-dict.implicits], dict)
+		}
+		rd.TypeParams = append(rd.TypeParams, tp)
+	}
+	for _, idx := range dict.derived {
+		rd.Derived = append(rd.Derived, derivedReport{TypeIndex: int(idx), Type: pr.typeString(typeInfo{idx: idx}, dict)})
+	}
+	for _, info := range dict.methodExprs {
+		rd.MethodExprs = append(rd.MethodExprs, methodExprReport{TypeParam: dict.tparamName(info.tparam), Method: info.method})
+	}
+	for _, info := range dict.subdicts {
+		var buf strings.Builder
+		pr.writeObj(&buf, info, dict)
+		rd.Subdicts = append(rd.Subdicts, subdictReport{ObjectIndex: int(info.idx), Object: buf.String()})
+	}
+	for _, info := range dict.rtypes {
+		rd.RTypes = append(rd.RTypes, pr.typeString(info, dict))
+	}
+	for _, info := range dict.itabs {
+		rd.Itabs = append(rd.Itabs, itabReport{Type: pr.typeString(info.typ, dict), Iface: pr.typeString(info.iface, dict)})
+	}
+	return rd
+}
+
+// refStrings returns the elements referenced by the specified element,
+// or nil if its reference table can't be read.
+func refStrings(pd *pkgbits.PkgDecoder, k pkgbits.SectionKind, idx int) []string {
+	refs, err := elemRefs(pd, elemKey{k, pkgbits.Index(idx)})
+	if err != nil {
+		return nil
+	}
+	strs := make([]string, len(refs))
+	for i, ref := range refs {
+		strs[i] = ref.String()
+	}
+	return strs
+}
+
+// writeJSON writes rep as indented JSON.
+func (rep *report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+// writeText writes rep in the human-readable format.
+func (rep *report) writeText(w io.Writer) {
+	fmt.Fprintln(w, "╔═══════════════════════════════════════════════════════════════╗")
+	fmt.Fprintln(w, "║                   Unified IR Binary Format                    ║")
+	fmt.Fprintln(w, "╚═══════════════════════════════════════════════════════════════╝")
+	fmt.Fprintln(w)
+
+	// Show the object header
+	fmt.Fprintln(w, "=== Build Metadata ===")
+	b := rep.Build
+	if b.ImportPath != "" {
+		fmt.Fprintf(w, "Import Path: %s (from %s)\n", b.ImportPath, b.ImportPathSource)
+	} else {
+		fmt.Fprintf(w, "Import Path: (unknown)\n")
+	}
+	if b.FoundBy != "" {
+		fmt.Fprintf(w, "File: %s\n", b.File)
+	}
+	fmt.Fprintf(w, "Container: %s\n", b.Container)
+	if h := b.Header; h != nil {
+		target := h.GOOS + "/" + h.GOARCH
+		if h.ArchVariant != "" {
+			target += " (" + h.ArchVariant + ")"
+		}
+		fmt.Fprintf(w, "Target: %s\n", target)
+		fmt.Fprintf(w, "Toolchain: %s\n", h.Toolchain)
+		if len(h.Experiments) > 0 {
+			fmt.Fprintf(w, "Experiments: %s\n", strings.Join(h.Experiments, ", "))
+		} else {
+			fmt.Fprintf(w, "Experiments: (none)\n")
+		}
+		if h.BuildID != "" {
+			fmt.Fprintf(w, "Build ID: %s\n", h.BuildID)
+		} else {
+			fmt.Fprintf(w, "Build ID: (none)\n")
+		}
+		if h.Main {
+			fmt.Fprintf(w, "Package main: true\n")
+		}
+		for _, line := range h.Other {
+			fmt.Fprintf(w, "Other: %s\n", line)
+		}
+	} else {
+		fmt.Fprintln(w, "(raw Unified IR has no object header)")
+	}
+	fmt.Fprintln(w)
+
+	// Show format metadata
+	fmt.Fprintln(w, "=== Format Metadata ===")
+	fmt.Fprintf(w, "Version: V%d\n", rep.Format.Version)
+	fmt.Fprintf(w, "Sync Markers: %v\n", rep.Format.SyncMarkers)
+	fmt.Fprintf(w, "Total Elements: %d\n", rep.Format.TotalElements)
+	if rep.Format.FingerprintVerified {
+		fmt.Fprintf(w, "Fingerprint: %s (verified)\n", rep.Format.Fingerprint)
+	} else {
+		fmt.Fprintf(w, "Fingerprint: %s (MISMATCH: data hashes to %s)\n", rep.Format.Fingerprint, rep.Format.ComputedFingerprint)
+	}
+	fmt.Fprintln(w)
+
+	// Show section statistics
+	fmt.Fprintln(w, "=== Section Statistics ===")
+	for _, sec := range rep.Sections {
+		fmt.Fprintf(w, "  %-16s: %4d elements\n", sec.Name, sec.Elements)
+	}
+	fmt.Fprintln(w)
+
+	// Show string table
+	fmt.Fprintln(w, "=== SectionString (Deduplicated Strings) ===")
+	stringCount := rep.count(pkgbits.SectionString)
+	if stringCount > 0 {
+		fmt.Fprintf(w, "Total strings: %d\n", stringCount)
+		if len(rep.Strings) < stringCount {
+			fmt.Fprintf(w, "(showing first %d)\n", len(rep.Strings))
+		}
+		fmt.Fprintln(w)
+
+		for _, s := range rep.Strings {
+			str := s.Value
+			if len(str) > 80 {
+				str = str[:77] + "..."
+			}
+			// Escape special characters
+			str = strings.ReplaceAll(str, "\n", "\\n")
+			str = strings.ReplaceAll(str, "\t", "\\t")
+			fmt.Fprintf(w, "  [%3d] %q\n", s.Index, str)
+		}
+		writeMore(w, len(rep.Strings), stringCount)
+	} else {
+		fmt.Fprintln(w, "  (empty)")
+	}
+	fmt.Fprintln(w)
+
+	// Show position bases (source files)
+	fmt.Fprintln(w, "=== SectionPosBase (Source File Locations) ===")
+	if posBaseCount := rep.count(pkgbits.SectionPosBase); posBaseCount > 0 {
+		for _, b := range rep.PosBases {
+			if b.FileBase {
+				fmt.Fprintf(w, "  [%d] %s (file base)\n", b.Index, b.Filename)
+			} else {
+				// Line base, from a //line directive.
+				start := fmt.Sprintf("line %d", b.Line)
+				if b.Col != 0 {
+					start += fmt.Sprintf(", column %d", b.Col)
+				}
+				fmt.Fprintf(w, "  [%d] %s (line base at %s, starting at %s)\n", b.Index, b.Filename, b.Pos, start)
+			}
+		}
+		writeMore(w, len(rep.PosBases), posBaseCount)
+	} else {
+		fmt.Fprintln(w, "  (none)")
+	}
+	fmt.Fprintln(w)
+
+	// Show package table
+	fmt.Fprintln(w, "=== SectionPkg (Package References) ===")
+	if pkgCount := rep.count(pkgbits.SectionPkg); pkgCount > 0 {
+		for _, p := range rep.Packages {
+			switch {
+			case p.Error != "":
+				fmt.Fprintf(w, "  [%d] (error reading package: %s)\n", p.Index, p.Error)
+			case p.Self && p.Path == "":
+				fmt.Fprintf(w, "  [%d] <self> (name: %s)\n", p.Index, p.Name)
+			case p.Self:
+				fmt.Fprintf(w, "  [%d] %s (name: %s, self)\n", p.Index, p.Path, p.Name)
+			default:
+				fmt.Fprintf(w, "  [%d] %s (name: %s)\n", p.Index, p.Path, p.Name)
+				if p.Archive != "" {
+					fmt.Fprintf(w, "      archive: %s\n", p.Archive)
+				}
+			}
+		}
+		writeMore(w, len(rep.Packages), pkgCount)
+	} else {
+		fmt.Fprintln(w, "  (none)")
+	}
+	fmt.Fprintln(w)
+
+	// Show type table
+	fmt.Fprintln(w, "=== SectionType (Type Definitions) ===")
+	typeCount := rep.count(pkgbits.SectionType)
+	fmt.Fprintf(w, "Total types: %d\n", typeCount)
+	if typeCount > 0 {
+		fmt.Fprintln(w, "($N is type parameter N, derived#N is derived type N of the owning dictionary)")
+		fmt.Fprintln(w)
+		for _, t := range rep.Types {
+			if t.Error != "" {
+				fmt.Fprintf(w, "  [%d] (error reading type: %s)\n", t.Index, t.Error)
+				continue
+			}
+			fmt.Fprintf(w, "  [%d] %-9s %s\n", t.Index, t.Code, t.Type)
+		}
+		writeMore(w, len(rep.Types), typeCount)
+	}
+	fmt.Fprintln(w)
+
+	// Show object table
+	fmt.Fprintln(w, "=== SectionObj (Object Declarations) ===")
+	if objCount := rep.count(pkgbits.SectionObj); objCount > 0 {
+		fmt.Fprintf(w, "Total objects: %d\n", objCount)
+		for _, k := range rep.ObjectKinds {
+			fmt.Fprintf(w, "  %-10s: %d\n", k.Tag, k.Count)
+		}
+		fmt.Fprintln(w)
+
+		for _, o := range rep.Objects {
+			if o.Error != "" {
+				fmt.Fprintf(w, "  [%d] (error reading object: %s)\n", o.Index, o.Error)
+				continue
+			}
+			fmt.Fprintf(w, "  [%d] %-5s %s\n", o.Index, o.Tag, o.Decl)
+			if o.Tag == objTagName(pkgbits.ObjStub) {
+				continue
+			}
+			fmt.Fprintf(w, "        pos: %s\n", o.Pos)
+			for _, line := range o.Dict {
+				fmt.Fprintf(w, "        dict: %s\n", line)
+			}
+
+			// Show the compiler extension data next to what it
+			// describes.
+			if o.Ext != "" {
+				fmt.Fprintf(w, "        ext: %s\n", o.Ext)
+			}
+			for _, m := range o.Methods {
+				fmt.Fprintf(w, "        %s\n", m.Decl)
+				fmt.Fprintf(w, "          pos: %s\n", m.Pos)
+				if m.Ext != "" {
+					fmt.Fprintf(w, "          ext: %s\n", m.Ext)
+				}
+			}
+		}
+		writeMore(w, len(rep.Objects), objCount)
+	} else {
+		fmt.Fprintln(w, "  (none)")
+	}
+	fmt.Fprintln(w)
+
+	// Show public root (what importers see)
+	fmt.Fprintln(w, "=== SectionMeta - Public Root (Package Exports) ===")
+	pub := rep.PublicRoot
+	if pub.Path != "" {
+		fmt.Fprintf(w, "Package: %s (name: %s)\n", pub.Path, pub.Name)
+	} else {
+		fmt.Fprintf(w, "Package: <self> (name: %s)\n", pub.Name)
+	}
+	if pub.HasInit != nil {
+		fmt.Fprintf(w, "Has init: %v\n", *pub.HasInit)
+	}
+	fmt.Fprintf(w, "Exported objects: %d (in importer order)\n", pub.ExportCount)
+	if pub.ExportCount > 0 {
+		fmt.Fprintln(w)
+		for _, e := range pub.Exports {
+			if e.Error != "" {
+				fmt.Fprintf(w, "  [%d] (error reading object %d: %s)\n", e.Index, e.ObjectIndex, e.Error)
+				continue
+			}
+			fmt.Fprintf(w, "  [%d] %-5s %s (object index: %d)\n", e.Index, e.Tag, e.Decl, e.ObjectIndex)
+		}
+		writeMore(w, len(pub.Exports), pub.ExportCount)
+	}
+	fmt.Fprintln(w)
+
+	// Show private root (function bodies)
+	fmt.Fprintln(w, "=== SectionMeta - Private Root (Function Bodies & Internal Data) ===")
+	priv := rep.PrivateRoot
+	fmt.Fprintf(w, "Has .inittask: %v\n", priv.HasInittask)
+	fmt.Fprintf(w, "Function bodies: %d\n", priv.BodyCount)
+	if priv.BodyCount > 0 {
+		fmt.Fprintln(w)
+		writeBodies(w, priv.Bodies)
+		writeMore(w, len(priv.Bodies), priv.BodyCount)
+	}
+	fmt.Fprintln(w)
+
+	// Show the generic function bodies
+	fmt.Fprintln(w, "=== SectionBody - Generic Function Bodies (compiled by importers) ===")
+	gen := rep.GenericBodies
+	fmt.Fprintf(w, "Generic function bodies: %d\n", gen.BodyCount)
+	if gen.BodyCount > 0 {
+		fmt.Fprintln(w)
+		writeBodies(w, gen.Bodies)
+		writeMore(w, len(gen.Bodies), gen.BodyCount)
+	}
+}
+
+// writeBodies writes function bodies with their source.
+func writeBodies(w io.Writer, bodies []bodyReport) {
+	for _, b := range bodies {
+		fmt.Fprintf(w, "  [%d] %s.%s (body index: %d)\n", b.Index, b.Package, b.Symbol, b.BodyIndex)
+		switch b.Error {
+		case "":
+		case errNoDecl:
+			fmt.Fprintf(w, "      (%s)\n", b.Error)
+			continue
+		default:
+			fmt.Fprintf(w, "      (error reading body: %s)\n", b.Error)
+			continue
+		}
+		for _, line := range strings.Split(b.Source, "\n") {
+			fmt.Fprintf(w, "      %s\n", line)
+		}
+	}
+}
+
+// count returns the number of elements in section k.
+func (rep *report) count(k pkgbits.SectionKind) int {
+	return rep.Sections[k].Elements
+}
+
+// writeMore notes how many of total entries were left out, if any.
+func writeMore(w io.Writer, shown, total int) {
+	if shown < total {
+		fmt.Fprintf(w, "  ... and %d more\n", total-shown)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestJSONReport(t *testing.T) {
+	rep, err := newReport(rawFile(examplePackage()), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b1, b2 bytes.Buffer
+	if err := rep.writeJSON(&b1); err != nil {
+		t.Fatal(err)
+	}
+	rep, err = newReport(rawFile(examplePackage()), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.writeJSON(&b2); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1.Bytes(), b2.Bytes()) {
+		t.Errorf("reports of the same export data differ")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b1.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"build", "format", "genericBodies", "objectKinds", "objects", "packages",
+		"posBases", "privateRoot", "publicRoot", "schemaVersion", "sections", "strings", "types",
+	}
+	if got := slices.Sorted(maps.Keys(fields)); !slices.Equal(got, want) {
+		t.Errorf("report has fields %v, want %v", got, want)
+	}
+
+	// The text forms of dictionaries and extension data are left to
+	// dictInfo and extInfo.
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(fields["objects"], &objects); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range objects {
+		if _, ok := o["dict"]; ok {
+			t.Errorf("object %s has a dict field", o["index"])
+		}
+		if _, ok := o["ext"]; ok {
+			t.Errorf("object %s has an ext field", o["index"])
+		}
+	}
+
+	var got report
+	if err := json.Unmarshal(b1.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SchemaVersion != reportSchemaVersion {
+		t.Errorf("schema version is %d, want %d", got.SchemaVersion, reportSchemaVersion)
+	}
+	if b := got.Build; b.ImportPath != testPath || b.ImportPathSource != "public root" || b.Container != "raw unified IR" || b.Header != nil {
+		t.Errorf("build is %+v", b)
+	}
+	if f := got.Format; f.Version != 2 || !f.SyncMarkers || !f.FingerprintVerified {
+		t.Errorf("format is %+v", f)
+	}
+
+	var exports []string
+	for _, e := range got.PublicRoot.Exports {
+		exports = append(exports, e.Decl)
+	}
+	wantExports := []string{
+		"type Number interface{~int | ~float64}",
+		"type List[T any] struct{next *List[T]; val T}",
+		"func Sum[T Number](xs ...T) (s T)",
+		`const Greeting string = "hello\tworld"`,
+		"type Person struct{Name string}",
+		"func Describe(p *Person) string",
+	}
+	if !slices.Equal(exports, wantExports) {
+		t.Errorf("public root exports\n%s\nwant\n%s", strings.Join(exports, "\n"), strings.Join(wantExports, "\n"))
+	}
+	if got.PublicRoot.Path != testPath || got.PublicRoot.Name != "example" || got.PublicRoot.HasInit != nil {
+		t.Errorf("public root is %+v", got.PublicRoot)
+	}
+
+	var bodies []string
+	for _, b := range got.PrivateRoot.Bodies {
+		bodies = append(bodies, b.Symbol)
+		if b.Error != "" {
+			t.Errorf("body %s: %s", b.Symbol, b.Error)
+		}
+	}
+	if want := []string{"(*Person).String", "Describe"}; !slices.Equal(bodies, want) {
+		t.Errorf("private root lists bodies %v, want %v", bodies, want)
+	}
+	if n := got.GenericBodies.BodyCount; n != 2 {
+		t.Errorf("found %d generic bodies, want 2", n)
+	}
+}
+
+func TestStructuredExt(t *testing.T) {
+	rep, err := newReport(rawFile(examplePackage()), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range rep.Packages {
+		if p.Error != "" {
+			t.Errorf("package %d (%s): %s", p.Index, p.Path, p.Error)
+		}
+	}
+
+	found := 0
+	for _, o := range rep.Objects {
+		switch {
+		case strings.HasPrefix(o.Decl, "func Sum["):
+			found++
+			if e := o.ExtInfo; e == nil || !e.Generic || e.BodyIndex == nil || *e.BodyIndex != 0 {
+				t.Errorf("Sum has extension data %+v, want a generic body", e)
+			}
+			d := o.DictInfo
+			if d == nil || len(d.TypeParams) != 1 || d.TypeParams[0].Name != "T" || d.TypeParams[0].Bound != "Number" {
+				t.Fatalf("Sum has dictionary %+v, want type parameter T Number", d)
+			}
+			if len(d.Derived) != 2 || d.Derived[1].Type != "[]T" || !slices.Equal(d.RTypes, []string{"T"}) {
+				t.Errorf("Sum has dictionary %+v, want derived types T and []T", d)
+			}
+		case strings.HasPrefix(o.Decl, "type Person "):
+			found++
+			if e := o.ExtInfo; e == nil || e.TypeSym == nil || *e.TypeSym != 3 || e.PtrSym == nil || *e.PtrSym != 4 {
+				t.Errorf("Person has extension data %+v, want type symbols 3 and 4", e)
+			}
+			if len(o.Methods) != 1 {
+				t.Fatalf("Person has methods %+v, want String", o.Methods)
+			}
+			e := o.Methods[0].ExtInfo
+			if e == nil || e.Inline == nil || e.BodyIndex == nil || e.Generic {
+				t.Fatalf("Person.String has extension data %+v, want an inlinable body", e)
+			}
+			if e.ABI != "ABIInternal" || e.Inline.Cost != 7 || !e.Inline.CanDelayResults {
+				t.Errorf("Person.String has extension data %+v", e)
+			}
+			if len(e.Escapes) != 1 || e.Escapes[0].Param != "p" || e.Escapes[0].Desc != "leaks to result 0 (level 0)" {
+				t.Errorf("Person.String has escape notes %+v, want one for p", e.Escapes)
+			}
+		}
+	}
+	if found != 2 {
+		t.Errorf("found %d of Sum and Person", found)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jespino/unified-ir-reader/importer"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// An indexEntry describes a file with export data found by scan.
+type indexEntry struct {
+	pkgPath     string // "" if unknown
+	version     pkgbits.Version
+	fingerprint [8]byte
+	elems       [pkgbits.SectionBody + 1]int // element counts, by section
+	size        int64                        // size of the file
+	filename    string
+}
+
+// runScan implements the scan command: it finds every file with export
+// data under a directory, by default the build cache, and writes an
+// index of them to standard output or the -o file.
+func runScan(args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	output := flags.String("o", "", "Write the index to `file` rather than standard output")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s scan [-o file] [dir]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Indexes the export data of every archive and object file under dir,\n")
+		fmt.Fprintf(os.Stderr, "which defaults to the build cache ($GOCACHE)\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	var dir string
+	switch flags.NArg() {
+	case 0:
+		out, err := exec.Command("go", "env", "GOCACHE").Output()
+		if err != nil {
+			return fmt.Errorf("finding the build cache: %w", err)
+		}
+		dir = strings.TrimSpace(string(out))
+		if dir == "" || dir == "off" {
+			return fmt.Errorf("the build cache is disabled")
+		}
+	case 1:
+		dir = flags.Arg(0)
+	default:
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	entries, scanned, err := scanDir(dir)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		if err := writeIndex(os.Stdout, entries); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Scanned %d files in %s: %d with export data\n", scanned, dir, len(entries))
+		return nil
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := writeIndex(file, entries); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Scanned %d files in %s: %d with export data, indexed in %s\n", scanned, dir, len(entries), *output)
+	return nil
+}
+
+// scanDir returns the index entries of the files with export data
+// under dir, sorted by package path and file name, along with the
+// number of files looked at. Files that look like archives or object
+// files but can't be decoded are reported and skipped.
+func scanDir(dir string) (entries []indexEntry, scanned int, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		scanned++
+
+		switch c, err := sniffContainer(path); {
+		case err != nil:
+			return err
+		case c != importer.ArchiveContainer && c != importer.ObjectContainer:
+			// Raw unified IR has no magic to recognize it by.
+			return nil
+		}
+
+		entry, err := indexFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s: %v\n", path, err)
+			return nil
+		}
+		entries = append(entries, *entry)
+		return nil
+	})
+	if err != nil {
+		return nil, scanned, err
+	}
+
+	slices.SortFunc(entries, func(a, b indexEntry) int {
+		return cmp.Or(cmp.Compare(a.pkgPath, b.pkgPath), cmp.Compare(a.filename, b.filename))
+	})
+	return entries, scanned, nil
+}
+
+// sniffContainer reports which kind of container the named file is,
+// reading only its first bytes.
+func sniffContainer(filename string) (importer.Container, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return importer.UnknownContainer, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, len("go object "))
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return importer.UnknownContainer, err
+	}
+	return importer.DetectContainer(buf[:n]), nil
+}
+
+// indexFile returns the index entry of the named file.
+func indexFile(filename string) (entry *indexEntry, err error) {
+	f, err := readExportFile(input{filename: filename})
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := f.newDecoder(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The public root is decoded to find the package path.
+	defer func() {
+		if r := recover(); r != nil {
+			entry, err = nil, fmt.Errorf("reading roots: %v", r)
+		}
+	}()
+	pr := f.newPkgReader(decoder, nil)
+
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	entry = &indexEntry{
+		pkgPath:     pr.selfPath,
+		version:     decoder.Version(),
+		fingerprint: decoder.Fingerprint(),
+		size:        fi.Size(),
+		filename:    filename,
+	}
+	for k := range entry.elems {
+		entry.elems[k] = decoder.NumElems(pkgbits.SectionKind(k))
+	}
+	return entry, nil
+}
+
+// writeIndex writes entries to w as tab-separated values, one line per
+// file after a header line naming the columns.
+func writeIndex(w io.Writer, entries []indexEntry) error {
+	var buf bytes.Buffer
+	fmt.Fprint(&buf, "# package\tversion\tfingerprint\tsize")
+	for k := range len(indexEntry{}.elems) {
+		fmt.Fprintf(&buf, "\t%v", pkgbits.SectionKind(k))
+	}
+	fmt.Fprint(&buf, "\tfile\n")
+
+	for _, e := range entries {
+		path := e.pkgPath
+		if path == "" {
+			path = "<unknown>"
+		}
+		fmt.Fprintf(&buf, "%s\tV%d\t%x\t%d", path, e.version, e.fingerprint, e.size)
+		for _, n := range e.elems {
+			fmt.Fprintf(&buf, "\t%d", n)
+		}
+		fmt.Fprintf(&buf, "\t%s\n", e.filename)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	other := uirtest.New(pkgbits.V1, -1, "example.com/other", "other")
+	for name, data := range map[string][]byte{
+		"a/example.a": uirtest.Archive(uirtest.Header, examplePackage(), "obj"),
+		"b/other.o":   uirtest.ObjectFile(uirtest.Header, other.Bytes(), "obj"),
+		"b/raw":       examplePackage(),
+		"b/text":      []byte("hello\n"),
+		"c/broken.a":  []byte("!<arch>\nbroken"),
+	} {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, data, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, scanned, err := scanDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scanned != 5 {
+		t.Errorf("scanned %d files, want 5", scanned)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("indexed %d files, want 2: %+v", len(entries), entries)
+	}
+	if e := entries[0]; e.pkgPath != "example.com/example" || e.version != pkgbits.V2 || e.filename != filepath.Join(dir, "a/example.a") {
+		t.Errorf("first entry is %+v", e)
+	}
+	if e := entries[1]; e.pkgPath != "example.com/other" || e.version != pkgbits.V1 || e.elems[pkgbits.SectionPkg] != 1 {
+		t.Errorf("second entry is %+v", e)
+	}
+
+	var index strings.Builder
+	if err := writeIndex(&index, entries); err != nil {
+		t.Fatal(err)
+	}
+	data := index.String()
+	lines := strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("index has %d lines, want 3:\n%s", len(lines), data)
+	}
+	if want := "# package\tversion\tfingerprint\tsize\tSectionString\t"; !strings.HasPrefix(lines[0], want) {
+		t.Errorf("index header is %q, want prefix %q", lines[0], want)
+	}
+	for i, e := range entries {
+		fields := strings.Split(lines[i+1], "\t")
+		if len(fields) != 15 || fields[0] != e.pkgPath || fields[len(fields)-1] != e.filename {
+			t.Errorf("index line %d is %q", i+1, lines[i+1])
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// A typeInfo is a type reference as encoded in an element bitstream.
+// If derived is true, idx is an index into the current dictionary's
+// derived types; otherwise, it is a SectionType index.
+type typeInfo struct {
+	idx     pkgbits.Index
+	derived bool
+}
+
+// An objInfo is a reference to an object, possibly instantiated with
+// explicit type arguments.
+type objInfo struct {
+	idx       pkgbits.Index
+	explicits []typeInfo
+}
+
+// A typeNode is a decoded SectionType element. Which fields are set
+// depends on code; references to other types are left as typeInfos so
+// they can be resolved within different dictionaries.
+type typeNode struct {
+	code pkgbits.CodeType
+
+	kind  types.BasicKind // TypeBasic
+	obj   objInfo         // TypeNamed
+	index int             // TypeTypeParam
+	len   uint64          // TypeArray
+	dir   types.ChanDir   // TypeChan
+
+	key  typeInfo // TypeMap
+	elem typeInfo // TypeArray, TypeChan, TypeMap, TypePointer, TypeSlice
+
+	sig *signature // TypeSignature
+
+	fields []field // TypeStruct
+
+	methods   []method   // TypeInterface
+	embeddeds []typeInfo // TypeInterface
+	implicit  bool       // TypeInterface
+
+	terms []term // TypeUnion
+}
+
+// A signature is a decoded function signature.
+type signature struct {
+	params   []param
+	results  []param
+	variadic bool
+}
+
+// A param is a function parameter or result.
+type param struct {
+	name string
+	typ  typeInfo
+}
+
+// A field is a struct field.
+type field struct {
+	name     string
+	typ      typeInfo
+	tag      string
+	embedded bool
+}
+
+// A method is an interface method.
+type method struct {
+	name string
+	sig  *signature
+}
+
+// A term is a union term.
+type term struct {
+	tilde bool
+	typ   typeInfo
+}
+
+// @@@ Types
+
+// typInfo reads a type reference from the bitstream.
+func (r *reader) typInfo() typeInfo {
+	r.Sync(pkgbits.SyncType)
+	if r.Bool() {
+		return typeInfo{idx: pkgbits.Index(r.Len()), derived: true}
+	}
+	return typeInfo{idx: r.Reloc(pkgbits.SectionType), derived: false}
+}
+
+// typIdx returns the SectionType element referred to by info, reading
+// it first if needed. It returns nil for derived types that dict
+// cannot resolve.
+func (pr *pkgReader) typIdx(info typeInfo, dict *readerDict) *typeNode {
+	idx := info.idx
+	if info.derived {
+		if dict == nil || int(idx) >= len(dict.derived) {
+			return nil
+		}
+		idx = dict.derived[idx]
+	}
+
+	if typ := pr.typs[idx]; typ != nil {
+		return typ
+	}
+
+	typ := pr.newReader(pkgbits.SectionType, idx, pkgbits.SyncTypeIdx).doTyp()
+	pr.typs[idx] = typ
+	return typ
+}
+
+func (r *reader) doTyp() *typeNode {
+	typ := &typeNode{code: pkgbits.CodeType(r.Code(pkgbits.SyncType))}
+
+	switch typ.code {
+	default:
+		panic(fmt.Errorf("unexpected type code: %v", typ.code))
+
+	case pkgbits.TypeBasic:
+		typ.kind = types.BasicKind(r.Len())
+	case pkgbits.TypeNamed:
+		typ.obj = r.objInfo()
+	case pkgbits.TypeTypeParam:
+		typ.index = r.Len()
+	case pkgbits.TypeArray:
+		typ.len = r.Uint64()
+		typ.elem = r.typInfo()
+	case pkgbits.TypeChan:
+		typ.dir = types.ChanDir(r.Len())
+		typ.elem = r.typInfo()
+	case pkgbits.TypeMap:
+		typ.key = r.typInfo()
+		typ.elem = r.typInfo()
+	case pkgbits.TypePointer:
+		typ.elem = r.typInfo()
+	case pkgbits.TypeSignature:
+		typ.sig = r.signature()
+	case pkgbits.TypeSlice:
+		typ.elem = r.typInfo()
+
+	case pkgbits.TypeStruct:
+		typ.fields = make([]field, r.Len())
+		for i := range typ.fields {
+			f := &typ.fields[i]
+			r.pos()
+			_, f.name = r.selector()
+			f.typ = r.typInfo()
+			f.tag = r.String()
+			f.embedded = r.Bool()
+		}
+
+	case pkgbits.TypeInterface:
+		typ.methods = make([]method, r.Len())
+		typ.embeddeds = make([]typeInfo, r.Len())
+		typ.implicit = len(typ.methods) == 0 && len(typ.embeddeds) == 1 && r.Bool()
+		for i := range typ.methods {
+			m := &typ.methods[i]
+			r.pos()
+			_, m.name = r.selector()
+			m.sig = r.signature()
+		}
+		for i := range typ.embeddeds {
+			typ.embeddeds[i] = r.typInfo()
+		}
+
+	case pkgbits.TypeUnion:
+		typ.terms = make([]term, r.Len())
+		for i := range typ.terms {
+			typ.terms[i] = term{tilde: r.Bool(), typ: r.typInfo()}
+		}
+	}
+
+	return typ
+}
+
+// objInfo reads an object reference from the bitstream.
+func (r *reader) objInfo() objInfo {
+	r.Sync(pkgbits.SyncObject)
+	if r.Version().Has(pkgbits.DerivedFuncInstance) {
+		r.Bool()
+	}
+	info := objInfo{idx: r.Reloc(pkgbits.SectionObj)}
+	info.explicits = make([]typeInfo, r.Len())
+	for i := range info.explicits {
+		info.explicits[i] = r.typInfo()
+	}
+	return info
+}
+
+func (r *reader) signature() *signature {
+	r.Sync(pkgbits.SyncSignature)
+	return &signature{
+		params:   r.params(),
+		results:  r.params(),
+		variadic: r.Bool(),
+	}
+}
+
+func (r *reader) params() []param {
+	r.Sync(pkgbits.SyncParams)
+	params := make([]param, r.Len())
+	for i := range params {
+		params[i] = r.param()
+	}
+	return params
+}
+
+func (r *reader) param() param {
+	r.Sync(pkgbits.SyncParam)
+	r.pos()
+	_, name := r.localIdent()
+	return param{name: name, typ: r.typInfo()}
+}
+
+// @@@ Formatting
+
+// typeString returns the Go syntax for the type referred to by info,
+// resolving derived types and type parameters within dict.
+func (pr *pkgReader) typeString(info typeInfo, dict *readerDict) string {
+	var buf strings.Builder
+	pr.writeType(&buf, info, dict)
+	return buf.String()
+}
+
+func (pr *pkgReader) writeType(buf *strings.Builder, info typeInfo, dict *readerDict) {
+	typ := pr.typIdx(info, dict)
+	if typ == nil {
+		fmt.Fprintf(buf, "derived#%d", info.idx)
+		return
+	}
+	pr.writeTypeNode(buf, typ, dict)
+}
+
+func (pr *pkgReader) writeTypeNode(buf *strings.Builder, typ *typeNode, dict *readerDict) {
+	switch typ.code {
+	case pkgbits.TypeBasic:
+		if int(typ.kind) < len(types.Typ) {
+			buf.WriteString(types.TypeString(types.Typ[typ.kind], nil))
+		} else {
+			fmt.Fprintf(buf, "basic#%d", typ.kind)
+		}
+
+	case pkgbits.TypeNamed:
+		pr.writeObj(buf, typ.obj, dict)
+
+	case pkgbits.TypeTypeParam:
+		buf.WriteString(dict.tparamName(typ.index))
+
+	case pkgbits.TypeArray:
+		fmt.Fprintf(buf, "[%d]", typ.len)
+		pr.writeType(buf, typ.elem, dict)
+
+	case pkgbits.TypeChan:
+		switch typ.dir {
+		case types.SendOnly:
+			buf.WriteString("chan<- ")
+		case types.RecvOnly:
+			buf.WriteString("<-chan ")
+		default:
+			buf.WriteString("chan ")
+			// "chan (<-chan T)" needs parentheses to parse as intended.
+			if elem := pr.typIdx(typ.elem, dict); elem != nil && elem.code == pkgbits.TypeChan && elem.dir == types.RecvOnly {
+				buf.WriteByte('(')
+				pr.writeType(buf, typ.elem, dict)
+				buf.WriteByte(')')
+				return
+			}
+		}
+		pr.writeType(buf, typ.elem, dict)
+
+	case pkgbits.TypeMap:
+		buf.WriteString("map[")
+		pr.writeType(buf, typ.key, dict)
+		buf.WriteByte(']')
+		pr.writeType(buf, typ.elem, dict)
+
+	case pkgbits.TypePointer:
+		buf.WriteByte('*')
+		pr.writeType(buf, typ.elem, dict)
+
+	case pkgbits.TypeSignature:
+		buf.WriteString("func")
+		pr.writeSignature(buf, typ.sig, dict)
+
+	case pkgbits.TypeSlice:
+		buf.WriteString("[]")
+		pr.writeType(buf, typ.elem, dict)
+
+	case pkgbits.TypeStruct:
+		buf.WriteString("struct{")
+		for i, f := range typ.fields {
+			if i > 0 {
+				buf.WriteString("; ")
+			}
+			if !f.embedded {
+				buf.WriteString(f.name)
+				buf.WriteByte(' ')
+			}
+			pr.writeType(buf, f.typ, dict)
+			if f.tag != "" {
+				buf.WriteByte(' ')
+				buf.WriteString(quoteTag(f.tag))
+			}
+		}
+		buf.WriteByte('}')
+
+	case pkgbits.TypeInterface:
+		if typ.implicit {
+			pr.writeType(buf, typ.embeddeds[0], dict)
+			return
+		}
+		if len(typ.methods) == 0 && len(typ.embeddeds) == 0 {
+			buf.WriteString("any")
+			return
+		}
+		buf.WriteString("interface{")
+		for i, m := range typ.methods {
+			if i > 0 {
+				buf.WriteString("; ")
+			}
+			buf.WriteString(m.name)
+			pr.writeSignature(buf, m.sig, dict)
+		}
+		for i, embedded := range typ.embeddeds {
+			if i > 0 || len(typ.methods) > 0 {
+				buf.WriteString("; ")
+			}
+			pr.writeType(buf, embedded, dict)
+		}
+		buf.WriteByte('}')
+
+	case pkgbits.TypeUnion:
+		for i, t := range typ.terms {
+			if i > 0 {
+				buf.WriteString(" | ")
+			}
+			if t.tilde {
+				buf.WriteByte('~')
+			}
+			pr.writeType(buf, t.typ, dict)
+		}
+
+	default:
+		fmt.Fprintf(buf, "<%s>", typeCodeName(typ.code))
+	}
+}
+
+// writeObj writes the qualified name of the referenced object,
+// followed by its type arguments, if any.
+func (pr *pkgReader) writeObj(buf *strings.Builder, info objInfo, dict *readerDict) {
+	buf.WriteString(pr.objName(info.idx))
+	if len(info.explicits) == 0 {
+		return
+	}
+	buf.WriteByte('[')
+	for i, targ := range info.explicits {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		pr.writeType(buf, targ, dict)
+	}
+	buf.WriteByte(']')
+}
+
+// writeSignature writes sig's parameter and result lists, without the
+// leading "func" keyword.
+func (pr *pkgReader) writeSignature(buf *strings.Builder, sig *signature, dict *readerDict) {
+	pr.writeParams(buf, sig.params, sig.variadic, dict)
+
+	switch {
+	case len(sig.results) == 0:
+	case len(sig.results) == 1 && sig.results[0].name == "":
+		buf.WriteByte(' ')
+		pr.writeType(buf, sig.results[0].typ, dict)
+	default:
+		buf.WriteByte(' ')
+		pr.writeParams(buf, sig.results, false, dict)
+	}
+}
+
+func (pr *pkgReader) writeParams(buf *strings.Builder, params []param, variadic bool, dict *readerDict) {
+	buf.WriteByte('(')
+	for i, p := range params {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if p.name != "" {
+			buf.WriteString(p.name)
+			buf.WriteByte(' ')
+		}
+		if variadic && i == len(params)-1 {
+			// The final parameter of a variadic signature is encoded as
+			// a slice type; print it as "...T" instead.
+			if typ := pr.typIdx(p.typ, dict); typ != nil && typ.code == pkgbits.TypeSlice {
+				buf.WriteString("...")
+				pr.writeType(buf, typ.elem, dict)
+				continue
+			}
+		}
+		pr.writeType(buf, p.typ, dict)
+	}
+	buf.WriteByte(')')
+}
+
+// quoteTag returns a struct tag as a Go string literal, preferring a
+// raw string literal where possible.
+func quoteTag(tag string) string {
+	if strconv.CanBackquote(tag) {
+		return "`" + tag + "`"
+	}
+	return strconv.Quote(tag)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+func TestTypeString(t *testing.T) {
+	w := uirtest.New(pkgbits.V2, 0, testPath, "example")
+	ioPkg := w.Pkg("io", "io")
+	errorObj := w.NewObject(w.Pkg("builtin", ""), "error", pkgbits.ObjStub)
+	reader := w.NewObject(ioPkg, "Reader", pkgbits.ObjStub)
+
+	intType := w.Basic(types.Int)
+	stringType := w.Basic(types.String)
+	errorType := w.Named(errorObj.Ref())
+	readerType := w.Named(reader.Ref())
+
+	tests := []struct {
+		typ  uirtest.Type
+		want string
+	}{
+		{intType, "int"},
+		{w.Basic(types.UnsafePointer), "unsafe.Pointer"},
+		{w.Basic(types.UntypedFloat), "untyped float"},
+		{w.Array(4, w.Basic(types.Byte)), "[4]uint8"},
+		{w.Chan(types.SendRecv, w.Chan(types.RecvOnly, intType)), "chan (<-chan int)"},
+		{w.Chan(types.SendOnly, w.Chan(types.SendRecv, intType)), "chan<- chan int"},
+		{w.Map(stringType, w.Slice(intType)), "map[string][]int"},
+		{w.Pointer(readerType), "*io.Reader"},
+		{w.Func(&uirtest.Signature{
+			Params: []uirtest.Param{
+				{Name: "format", Type: stringType},
+				{Name: "args", Type: w.Slice(w.Interface(nil, nil, false))},
+			},
+			Results:  []uirtest.Param{{Type: errorType}},
+			Variadic: true,
+		}), "func(format string, args ...any) error"},
+		{w.Func(&uirtest.Signature{
+			Results: []uirtest.Param{{Name: "n", Type: intType}, {Name: "err", Type: errorType}},
+		}), "func() (n int, err error)"},
+		{w.Struct(
+			uirtest.Field{Name: "Reader", Type: readerType, Embedded: true},
+			uirtest.Field{Name: "n", Type: intType, Tag: `json:"n"`},
+			uirtest.Field{Name: "s", Type: stringType, Tag: "a`b"},
+		), "struct{io.Reader; n int `json:\"n\"`; s string \"a`b\"}"},
+		{w.Interface([]uirtest.IfaceMethod{{
+			Name: "Close",
+			Sig:  &uirtest.Signature{Results: []uirtest.Param{{Type: errorType}}},
+		}}, []uirtest.Type{readerType}, false), "interface{Close() error; io.Reader}"},
+		{w.Interface(nil, []uirtest.Type{w.Union(
+			uirtest.Term{Tilde: true, Type: intType},
+			uirtest.Term{Type: stringType},
+		)}, true), "~int | string"},
+	}
+	errorObj.Flush()
+	reader.Flush()
+
+	pr := testReader(t, w.Bytes())
+	for _, test := range tests {
+		if got := pr.typeString(typeInfo{idx: test.typ.Idx}, nil); got != test.want {
+			t.Errorf("SectionType[%d] is %s, want %s", test.typ.Idx, got, test.want)
+		}
+	}
+}
+
+func TestDerivedTypeString(t *testing.T) {
+	pr := testReader(t, examplePackage())
+	_, list := exampleObj(t, pr, "List")
+	if got, want := pr.typeString(list.typ, list.dict), "struct{next *List[T]; val T}"; got != want {
+		t.Errorf("underlying type of List is %s, want %s", got, want)
+	}
+
+	// Without the dictionary, derived types cannot be resolved.
+	if got, want := pr.typeString(list.typ, nil), "derived#3"; got != want {
+		t.Errorf("underlying type of List without its dictionary is %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+// An elemKey identifies an element by section and index.
+type elemKey struct {
+	k   pkgbits.SectionKind
+	idx pkgbits.Index
+}
+
+// String returns e as "section:index", such as "SectionObj:12".
+func (e elemKey) String() string {
+	return fmt.Sprintf("%v:%d", e.k, e.idx)
+}
+
+// A decodedElem is an element reader recorded during validation.
+type decodedElem struct {
+	k   pkgbits.SectionKind
+	idx pkgbits.Index
+	r   *reader
+}
+
+// validateElems decodes every element reachable from the roots and
+// objects and returns a defect for each element that fails to decode
+// or has bytes left over afterwards, along with the number of elements
+// checked. String elements are raw data and are not checked.
+func (pr *pkgReader) validateElems() (errs []error, checked int) {
+	pr.decoded = []decodedElem{}
+	defer func() { pr.decoded = nil }()
+
+	failed := make(map[elemKey]bool)
+	try := func(k pkgbits.SectionKind, idx pkgbits.Index, decode func()) {
+		defer func() {
+			if r := recover(); r != nil {
+				failed[elemKey{k, idx}] = true
+				errs = append(errs, fmt.Errorf("%v[%d]: %v", k, idx, r))
+			}
+		}()
+		decode()
+	}
+
+	try(pkgbits.SectionMeta, pkgbits.PublicRootIdx, pr.readPublicRoot)
+	try(pkgbits.SectionMeta, pkgbits.PrivateRootIdx, pr.readPrivateRoot)
+
+	for i := range pr.NumElems(pkgbits.SectionPosBase) {
+		idx := pkgbits.Index(i)
+		try(pkgbits.SectionPosBase, idx, func() { pr.posBaseIdx(idx) })
+	}
+	for i := range pr.NumElems(pkgbits.SectionPkg) {
+		idx := pkgbits.Index(i)
+		try(pkgbits.SectionPkg, idx, func() { pr.pkgIdx(idx) })
+	}
+	for i := range pr.NumElems(pkgbits.SectionType) {
+		idx := pkgbits.Index(i)
+		try(pkgbits.SectionType, idx, func() { pr.typIdx(typeInfo{idx: idx}, nil) })
+	}
+
+	// Objects cover SectionName, SectionObjDict and SectionObj. Their
+	// extension data and bodies need the decoded object.
+	decls := make(map[string]funcDecl)
+	var bodies []pkgbits.Index
+	bodyDecls := make(map[pkgbits.Index]funcDecl)
+	var stubs []elemKey
+	for i := range pr.NumElems(pkgbits.SectionObj) {
+		idx := pkgbits.Index(i)
+		var obj *objNode
+		try(pkgbits.SectionObj, idx, func() { obj = pr.objIdx(idx) })
+		if obj == nil {
+			continue
+		}
+		if obj.tag == pkgbits.ObjStub {
+			// Stubs have an empty dictionary, and no object or
+			// extension data beyond an empty reference table and
+			// the sync marker that starts every element.
+			try(pkgbits.SectionObjDict, idx, func() { pr.objDictIdx(idx) })
+			for _, k := range []pkgbits.SectionKind{pkgbits.SectionObj, pkgbits.SectionObjExt} {
+				try(k, idx, func() {
+					r := pr.NewDecoder(k, idx, pkgbits.SyncObject1)
+					if len(r.Relocs) != 0 || r.Data.Len() != 0 {
+						panic(fmt.Sprintf("unexpected data for a stub: %d references, %d bytes", len(r.Relocs), r.Data.Len()))
+					}
+				})
+				stubs = append(stubs, elemKey{k, idx})
+			}
+			continue
+		}
+
+		var ext *objExt
+		try(pkgbits.SectionObjExt, idx, func() { ext = pr.objExtIdx(idx, obj) })
+
+		var fns []funcDecl
+		switch obj.tag {
+		case pkgbits.ObjFunc:
+			fns = []funcDecl{{obj: obj}}
+			decls[pr.funcSymName(obj, nil)] = fns[0]
+		case pkgbits.ObjType:
+			for _, m := range obj.methods {
+				fns = append(fns, funcDecl{obj: obj, m: m})
+				decls[pr.funcSymName(obj, m)] = fns[len(fns)-1]
+			}
+		}
+		if ext == nil {
+			continue
+		}
+		for j, f := range ext.funcs {
+			if !f.extended && j < len(fns) {
+				bodies = append(bodies, f.body)
+				bodyDecls[f.body] = fns[j]
+			}
+		}
+	}
+
+	// Inlinable bodies are listed by linker symbol in the private root.
+	syms := make([]string, 0, len(pr.bodies))
+	for sym := range pr.bodies {
+		syms = append(syms, sym)
+	}
+	sort.Strings(syms)
+	for _, sym := range syms {
+		idx := pr.bodies[sym]
+		decl, ok := decls[sym]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%v[%d]: no declaration for %s", pkgbits.SectionBody, idx, sym))
+			continue
+		}
+		bodies = append(bodies, idx)
+		bodyDecls[idx] = decl
+	}
+
+	for _, idx := range bodies {
+		decl := bodyDecls[idx]
+		try(pkgbits.SectionBody, idx, func() { pr.bodyString(idx, decl) })
+	}
+
+	// Report leftover bytes once per element, skipping elements whose
+	// decoding failed part way.
+	seen := make(map[elemKey]bool)
+	for _, d := range pr.decoded {
+		key := elemKey{d.k, d.idx}
+		if seen[key] || failed[key] {
+			continue
+		}
+		seen[key] = true
+		if n := d.r.Data.Len(); n != 0 {
+			errs = append(errs, fmt.Errorf("%v[%d]: %d trailing bytes after decoding", d.k, d.idx, n))
+		}
+	}
+	for key := range failed {
+		seen[key] = true
+	}
+	for _, key := range stubs {
+		seen[key] = true
+	}
+
+	return errs, len(seen)
+}
+
+// runValidate checks the export data of the input, including its
+// fingerprint, and prints every defect found. It reports whether the
+// file is sane.
+func runValidate(in input, cfg *importCfg) bool {
+	filename := in.String()
+	f, err := readExportFile(in)
+	if err != nil {
+		fmt.Printf("%s: %v\n", filename, err)
+		return false
+	}
+
+	errs, checked, total := validateFile(f, cfg)
+	for _, err := range errs {
+		fmt.Printf("%s: %v\n", filename, err)
+	}
+	if len(errs) > 0 {
+		fmt.Printf("%s: %d defects\n", filename, len(errs))
+		return false
+	}
+	fmt.Printf("%s: OK (%d of %d non-string elements checked)\n", filename, checked, total)
+	return true
+}
+
+// validateFile returns the defects of the file's export data, and the
+// number of non-string elements checked out of the total. A corrupt
+// header hides the elements, but the fingerprint and the rest of the
+// archive are still checked.
+func validateFile(f *exportFile, cfg *importCfg) (errs []error, checked, total int) {
+	decoder, err := f.newDecoder(cfg)
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		total = decoder.TotalElems() - decoder.NumElems(pkgbits.SectionString)
+		errs = decoder.Validate()
+	}
+	if len(errs) == 0 {
+		// Element decoding relies on sound element boundaries and
+		// reference tables.
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					errs = append(errs, fmt.Errorf("cannot read roots: %v", r))
+				}
+			}()
+			errs, checked = f.newPkgReader(decoder, cfg).validateElems()
+		}()
+	}
+
+	if stored, computed, ok := pkgbits.InputFingerprints(string(f.uirData[1:])); ok && stored != computed {
+		errs = append(errs, fmt.Errorf("fingerprint is %x, but the export data hashes to %x", stored, computed))
+	}
+	if f.archiveErr != nil {
+		errs = append(errs, f.archiveErr)
+	}
+	return errs, checked, total
+}
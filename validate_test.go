@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jespino/unified-ir-reader/internal/uirtest"
+	"github.com/jespino/unified-ir-reader/pkgbits"
+)
+
+func TestValidateElems(t *testing.T) {
+	pr := testReader(t, examplePackage())
+	errs, checked := pr.validateElems()
+	if len(errs) != 0 {
+		t.Errorf("example package has defects: %v", errs)
+	}
+	if total := pr.TotalElems() - pr.NumElems(pkgbits.SectionString); checked != total {
+		t.Errorf("checked %d elements, want %d", checked, total)
+	}
+
+	w := uirtest.New(pkgbits.V2, -1, testPath, "example")
+	f := w.NewObject(w.Self, "f", pkgbits.ObjFunc)
+	f.Sig = &uirtest.Signature{}
+	f.FuncExt = &uirtest.FuncExt{}
+	f.Flush()
+	body := w.NewBody()
+	body.Bool(true)
+	body.Bool(true)
+	bad := body.Flush()
+	w.InlineBody(testPath, "f", bad)
+	w.InlineBody(testPath, "g", bad)
+
+	errs, _ = testReader(t, w.Bytes()).validateElems()
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	for _, want := range []string{
+		fmt.Sprintf("SectionBody[%d]: no declaration for %s.g", bad, testPath),
+		fmt.Sprintf("SectionBody[%d]: unexpected decoding error", bad),
+	} {
+		found := false
+		for _, s := range got {
+			found = found || strings.HasPrefix(s, want)
+		}
+		if !found {
+			t.Errorf("defects %q lack %q", got, want)
+		}
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	errs, checked, total := validateFile(rawFile(examplePackage()), nil)
+	if len(errs) != 0 || checked != total {
+		t.Errorf("example package has defects %v, %d of %d elements checked", errs, checked, total)
+	}
+
+	// A corrupt header still leaves the fingerprint and the archive
+	// members to check.
+	data := examplePackage()
+	data[1+4+4] = 0xff // elemEndsEnds[SectionString]
+	archive := uirtest.Archive(uirtest.Header, data, strings.Repeat("obj", 100))
+	archive = archive[:bytes.Index(archive, []byte("_go_.o"))+100]
+	f, err := readExportFile(input{filename: writeTemp(t, "example.a", archive)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs, _, _ = validateFile(f, nil)
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	want := []string{"bad section count", "fingerprint is", "truncated archive"}
+	if len(got) != len(want) {
+		t.Fatalf("defects %q, want %d", got, len(want))
+	}
+	for i, s := range got {
+		if !strings.Contains(s, want[i]) {
+			t.Errorf("defect %d is %q, want mention of %q", i, s, want[i])
+		}
+	}
+}